@@ -81,6 +81,11 @@ func (g *Group) Get(key string) (ByteView, error) {
 	return g.load(key)
 }
 
+// 只查询本地缓存，未命中时不加载，也不输出日志
+func (g *Group) Lookup(key string) (ByteView, bool) {
+	return g.mainCache.get(key)
+}
+
 // 加载缓存（可能本地获取，也可能从其它节点获取）
 func (g *Group) load(key string) (value ByteView, err error) {
 	// loader.Do保证只触发一次
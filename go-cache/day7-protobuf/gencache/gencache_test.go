@@ -53,6 +53,18 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestLookup(t *testing.T) {
+	gen := NewGroup("lookup", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	if _, ok := gen.Lookup("Tom"); ok {
+		t.Fatalf("Lookup should not load missing key")
+	}
+	gen.Get("Tom")
+	if view, ok := gen.Lookup("Tom"); !ok || view.String() != "Tom" {
+		t.Fatalf("Lookup should return cached value")
+	}
+}
+
 func TestGetGroup(t *testing.T) {
 	groupName := "scores"
 	NewGroup(groupName, 2<<10, GetterFunc(
@@ -0,0 +1,165 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件名中hash的长度
+const assetHashLength = 8
+
+// 带hash的静态资源，css/lovecucu.css -> css/lovecucu.<hash>.css
+type assetManifest struct {
+	prefix string // 静态路由的完整路径，例如/assets
+	root   http.FileSystem
+
+	mu       sync.RWMutex
+	files    map[string]assetFile // 原始文件名 -> 带hash的文件名
+	original map[string]string    // 带hash的文件名 -> 原始文件名
+}
+
+// 计算hash时文件的修改时间和大小，变化后需重新计算
+type assetFile struct {
+	hashed  string
+	modTime time.Time
+	size    int64
+}
+
+func newAssetManifest(prefix string, root http.FileSystem) *assetManifest {
+	m := &assetManifest{
+		prefix:   prefix,
+		root:     root,
+		files:    make(map[string]assetFile),
+		original: make(map[string]string),
+	}
+	m.walk("/")
+	return m
+}
+
+// 启动时计算root下所有文件的hash
+func (m *assetManifest) walk(dir string) {
+	f, err := m.root.Open(dir)
+	if err != nil {
+		return
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if info.IsDir() {
+			m.walk(name)
+		} else {
+			m.add(name)
+		}
+	}
+}
+
+// 计算文件的hash并记录，替换掉旧的带hash的文件名，文件不存在时删除记录并返回false
+func (m *assetManifest) add(name string) (string, bool) {
+	file, ok := m.hashFile(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, exists := m.files[name]; exists {
+		delete(m.original, old.hashed)
+		delete(m.files, name)
+	}
+	if !ok {
+		return "", false
+	}
+	m.files[name] = file
+	m.original[file.hashed] = name
+	return file.hashed, true
+}
+
+func (m *assetManifest) hashFile(name string) (assetFile, bool) {
+	f, err := m.root.Open(name)
+	if err != nil {
+		return assetFile{}, false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		return assetFile{}, false
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return assetFile{}, false
+	}
+	ext := path.Ext(name)
+	return assetFile{
+		hashed:  strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(h.Sum(nil))[:assetHashLength] + ext,
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+	}, true
+}
+
+// 获取文件当前的带hash的文件名，修改时间或大小变化时重新计算
+func (m *assetManifest) current(name string) (string, bool) {
+	m.mu.RLock()
+	file, ok := m.files[name]
+	m.mu.RUnlock()
+	if ok {
+		if f, err := m.root.Open(name); err == nil {
+			stat, err := f.Stat()
+			f.Close()
+			if err == nil && stat.ModTime().Equal(file.modTime) && stat.Size() == file.size {
+				return file.hashed, true
+			}
+		}
+	}
+	return m.add(name)
+}
+
+// 获取带hash的文件名，启动后新增的文件按需计算；debug模式下文件修改后重新计算
+func (m *assetManifest) lookup(name string) (string, bool) {
+	if IsDebugging() {
+		return m.current(name)
+	}
+	m.mu.RLock()
+	file, ok := m.files[name]
+	m.mu.RUnlock()
+	if ok {
+		return file.hashed, true
+	}
+	return m.add(name)
+}
+
+// 将带hash的文件名还原为原始文件名，文件内容已变化时返回false，避免以永久缓存输出新的内容
+func (m *assetManifest) resolve(hashed string) (string, bool) {
+	m.mu.RLock()
+	name, ok := m.original[hashed]
+	m.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	if current, ok := m.current(name); !ok || current != hashed {
+		return "", false
+	}
+	return name, true
+}
+
+// 获取静态资源带hash的URL，例如AssetPath("css/lovecucu.css")返回/assets/css/lovecucu.<hash>.css，
+// 需使用StaticConfig.Fingerprint注册静态路由；模板中可使用{{ asset "css/lovecucu.css" }}
+func (engine *Engine) AssetPath(file string) string {
+	name := path.Clean("/" + file)
+	for _, m := range engine.assets {
+		if hashed, ok := m.lookup(name); ok {
+			return path.Join(m.prefix, hashed)
+		}
+	}
+	// 找不到文件时返回不带hash的路径
+	if len(engine.assets) > 0 {
+		return path.Join(engine.assets[0].prefix, name)
+	}
+	return file
+}
@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAssetFingerprint(t *testing.T) {
+	files := fstest.MapFS{
+		"css/lovecucu.css": {Data: []byte("body{color:red}")},
+	}
+	engine := New()
+	engine.StaticWithConfig("/assets", http.FS(files), StaticConfig{Fingerprint: true, MaxAge: time.Minute})
+
+	tmpl := template.Must(template.New("page").Funcs(engine.templateFuncs()).Parse(`{{ asset "css/lovecucu.css" }}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	url := buf.String()
+	if !regexp.MustCompile(`^/assets/css/lovecucu\.[0-9a-f]{8}\.css$`).MatchString(url) {
+		t.Fatalf("unexpected asset url %s", url)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "body{color:red}" || w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("hashed asset should be served immutable, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/assets/css/lovecucu.css", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("original asset should use configured caching, got %d %v", w.Code, w.Header())
+	}
+
+	if got := engine.AssetPath("js/missing.js"); got != "/assets/js/missing.js" {
+		t.Fatalf("missing asset should not be hashed, got %s", got)
+	}
+}
+
+func TestAssetFingerprintChanged(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.js")
+	if err := ioutil.WriteFile(file, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := New()
+	engine.StaticWithConfig("/assets", http.Dir(dir), StaticConfig{Fingerprint: true})
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	old := engine.AssetPath("app.js")
+	if err := ioutil.WriteFile(file, []byte("v2-changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 非debug模式下AssetPath不检查文件，但带hash的文件名在输出前校验内容
+	if got := engine.AssetPath("app.js"); got != old {
+		t.Fatalf("asset path should be cached outside debug mode, got %s", got)
+	}
+	if w := get(old); w.Code != http.StatusNotFound {
+		t.Fatalf("stale hashed asset should not be served immutable, got %d %v", w.Code, w.Header())
+	}
+
+	SetMode(DebugMode)
+	defer SetMode(TestMode)
+	ioutil.WriteFile(file, []byte("v3-changed-again"), 0644)
+	updated := engine.AssetPath("app.js")
+	if updated == old {
+		t.Fatal("asset should be re-hashed after change in debug mode")
+	}
+	if w := get(updated); w.Code != http.StatusOK || w.Body.String() != "v3-changed-again" || w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("new hashed asset should be served, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w := get(old); w.Code != http.StatusNotFound {
+		t.Fatalf("old hashed name should be dropped, got %d", w.Code)
+	}
+}
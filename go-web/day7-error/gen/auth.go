@@ -0,0 +1,115 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 认证通过后，用户名、token对应的用户或JWT claims保存在Context中的key
+const AuthPrincipalKey = "gen.principal"
+
+// BasicAuth的账号，用户名 -> 密码
+type Accounts map[string]string
+
+// 校验bearer token，返回认证后的用户信息
+type TokenValidator func(token string) (principal interface{}, err error)
+
+// 获取认证通过的用户信息，未认证时返回nil
+func (c *Context) Principal() interface{} {
+	principal, _ := c.Get(AuthPrincipalKey)
+	return principal
+}
+
+// 认证失败，返回401及WWW-Authenticate
+func unauthorized(c *Context, challenge string) {
+	c.SetHeader("WWW-Authenticate", challenge)
+	c.Fail(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+}
+
+// 定长比较，避免通过耗时推测密码
+func secureCompare(given string, actual string) bool {
+	g := sha256.Sum256([]byte(given))
+	a := sha256.Sum256([]byte(actual))
+	return subtle.ConstantTimeCompare(g[:], a[:]) == 1
+}
+
+// HTTP Basic认证，通过后用户名保存在AuthPrincipalKey
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(c *Context) {
+		user, password, ok := c.Req.BasicAuth()
+		if !ok {
+			unauthorized(c, challenge)
+			return
+		}
+		expected, exists := accounts[user]
+		// 用户不存在时同样做一次比较，保持耗时一致
+		if !secureCompare(password, expected) || !exists {
+			unauthorized(c, challenge)
+			return
+		}
+		c.Set(AuthPrincipalKey, user)
+		c.Next()
+	}
+}
+
+// 从Authorization中获取bearer token
+func bearerToken(c *Context) string {
+	auth := c.Req.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// bearer token失败时的challenge，见RFC 6750
+func bearerChallenge(realm string, err error) string {
+	challenge := "Bearer realm=" + strconv.Quote(realm)
+	if err != nil {
+		challenge += `, error="invalid_token", error_description=` + strconv.Quote(err.Error())
+	}
+	return challenge
+}
+
+// Bearer token认证，validator返回的用户信息保存在AuthPrincipalKey
+func BearerAuth(validator TokenValidator) HandlerFunc {
+	return BearerAuthForRealm(validator, "")
+}
+
+func BearerAuthForRealm(validator TokenValidator, realm string) HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+
+	return func(c *Context) {
+		token := bearerToken(c)
+		if token == "" {
+			unauthorized(c, bearerChallenge(realm, nil))
+			return
+		}
+		principal, err := validator(token)
+		if err != nil {
+			unauthorized(c, bearerChallenge(realm, err))
+			return
+		}
+		c.Set(AuthPrincipalKey, principal)
+		c.Next()
+	}
+}
+
+// base64url解码，JWT不带padding
+func decodeSegment(seg string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(seg, "="))
+}
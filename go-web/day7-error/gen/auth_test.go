@@ -0,0 +1,117 @@
+package gen
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 生成测试用的JWT
+func signJWT(alg string, claims JWTClaims, sign func(input string) []byte) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(input))
+}
+
+func signHS256(secret []byte) func(string) []byte {
+	return func(input string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		return mac.Sum(nil)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	engine := New()
+	engine.Use(BasicAuth(Accounts{"lovecucu": "secret"}))
+	engine.GET("/admin", func(c *Context) {
+		c.String(http.StatusOK, "%v", c.Principal())
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.SetBasicAuth("lovecucu", "secret")
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "lovecucu" {
+		t.Fatalf("valid account should pass, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/admin", nil)
+	req.SetBasicAuth("lovecucu", "wrong")
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Basic realm=") {
+		t.Fatalf("wrong password should return 401, got %d", w.Code)
+	}
+}
+
+func TestParseJWT(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+	config := JWTConfig{Secret: secret, Issuer: "gen", Audience: "api", Leeway: time.Minute,
+		now: func() time.Time { return now }}
+
+	valid := signJWT("HS256", JWTClaims{"sub": "lovecucu", "iss": "gen", "aud": []string{"api"},
+		"exp": now.Add(-30 * time.Second).Unix()}, signHS256(secret))
+	claims, err := ParseJWT(valid, config)
+	if err != nil || claims.String("sub") != "lovecucu" {
+		t.Fatalf("token within leeway should be valid, got %v", err)
+	}
+
+	cases := map[string]error{
+		signJWT("HS256", JWTClaims{"iss": "gen", "aud": "api", "exp": now.Add(-time.Hour).Unix()}, signHS256(secret)): ErrTokenExpired,
+		signJWT("HS256", JWTClaims{"iss": "gen", "aud": "api", "nbf": now.Add(time.Hour).Unix()}, signHS256(secret)):  ErrTokenNotValidYet,
+		signJWT("HS256", JWTClaims{"iss": "gen", "aud": "web"}, signHS256(secret)):                                    ErrTokenInvalidClaim,
+		signJWT("HS256", JWTClaims{"iss": "gen", "aud": "api"}, signHS256([]byte("other"))):                           ErrTokenSignature,
+		signJWT("none", JWTClaims{"iss": "gen", "aud": "api"}, func(string) []byte { return nil }):                    ErrTokenAlgorithm,
+	}
+	for token, want := range cases {
+		if _, err := ParseJWT(token, config); err != want {
+			t.Fatalf("expect %v, got %v", want, err)
+		}
+	}
+}
+
+func TestJWTAuthRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := New()
+	engine.Use(JWTAuth(JWTConfig{PublicKey: &key.PublicKey}))
+	engine.GET("/me", func(c *Context) {
+		c.String(http.StatusOK, c.Principal().(JWTClaims).String("sub"))
+	})
+
+	token := signJWT("RS256", JWTClaims{"sub": "lovecucu"}, func(input string) []byte {
+		digest := sha256.Sum256([]byte(input))
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return sig
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "lovecucu" {
+		t.Fatalf("valid RS256 token should pass, got %d %s", w.Code, w.Body.String())
+	}
+
+	// HS256签名的token不能通过只配置了公钥的中间件
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+signJWT("HS256", JWTClaims{"sub": "x"}, signHS256([]byte("k"))))
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("HS256 token should be rejected, got %d", w.Code)
+	}
+}
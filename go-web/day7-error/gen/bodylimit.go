@@ -0,0 +1,66 @@
+package gen
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// 解析multipart表单时保存在内存中的最大字节数，超出部分写入临时文件
+const defaultMultipartMemory = 32 << 20
+
+// 限制请求体的字节数，可用于Engine、RouterGroup或单个路由。
+// Content-Length超出时直接返回413，否则在读取超出时由ParseForm、BindJSON返回413。
+// 多层嵌套时以最小的限制为准
+func BodyLimit(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Req.ContentLength > limit {
+			c.bodyTooLarge()
+			return
+		}
+		if c.Req.Body != nil && c.Req.Body != http.NoBody {
+			c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// http.MaxBytesReader超出限制时返回的错误
+func isBodyTooLarge(err error) bool {
+	return errors.As(err, new(*http.MaxBytesError))
+}
+
+func (c *Context) bodyTooLarge() {
+	c.Fail(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+}
+
+// 将JSON请求体解析到obj，失败时返回400，请求体超出BodyLimit时返回413
+func (c *Context) BindJSON(obj interface{}) error {
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		if isBodyTooLarge(err) {
+			c.bodyTooLarge()
+		} else {
+			c.Fail(http.StatusBadRequest, "invalid request body: "+err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// 解析表单，失败时返回400，请求体超出BodyLimit时返回413，之后通过PostForm获取参数：
+//
+//	if c.ParseForm() != nil {
+//		return
+//	}
+//	name := c.PostForm("name")
+func (c *Context) ParseForm() error {
+	if err := c.parseForm(); err != nil {
+		if isBodyTooLarge(err) {
+			c.bodyTooLarge()
+		} else {
+			c.Fail(http.StatusBadRequest, "invalid form: "+err.Error())
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	engine := New()
+	engine.Use(BodyLimit(16))
+	engine.POST("/form", func(c *Context) {
+		if c.ParseForm() == nil {
+			c.String(http.StatusOK, c.PostForm("name"))
+		}
+	})
+	// PostForm不输出响应，由handler决定如何处理
+	engine.POST("/value", func(c *Context) {
+		c.String(http.StatusOK, "name=%q", c.PostForm("name"))
+	})
+	engine.POST("/json", func(c *Context) {
+		var body map[string]string
+		if c.BindJSON(&body) == nil {
+			c.String(http.StatusOK, body["name"])
+		}
+	})
+
+	post := func(path string, contentType string, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("/form", "application/x-www-form-urlencoded", "name=cucu", false); w.Code != http.StatusOK || w.Body.String() != "cucu" {
+		t.Fatalf("small form should pass, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/form", "application/x-www-form-urlencoded", "name="+strings.Repeat("a", 32), false); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized Content-Length should return 413, got %d", w.Code)
+	}
+	if w := post("/form", "application/x-www-form-urlencoded", "name="+strings.Repeat("a", 32), true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized form should return 413, got %d", w.Code)
+	}
+	if w := post("/value", "application/x-www-form-urlencoded", "name="+strings.Repeat("a", 32), true); w.Code != http.StatusOK || w.Body.String() != `name=""` {
+		t.Fatalf("PostForm should not write a response, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/form", "application/x-www-form-urlencoded", "name=%zz", false); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid form should return 400, got %d", w.Code)
+	}
+	if w := post("/json", "application/json", `{"name":"`+strings.Repeat("a", 32)+`"}`, true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized json should return 413, got %d", w.Code)
+	}
+	if w := post("/json", "application/json", `{"name":`, false); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid json should return 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,287 @@
+package gen
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 压缩中间件的配置
+type CompressConfig struct {
+	// 压缩级别，例如gzip.BestSpeed，为nil时使用gzip.DefaultCompression；
+	// 使用指针是为了区分未设置与gzip.NoCompression（0）
+	Level *int
+	// 响应体小于该字节数时不压缩，默认1024
+	MinLength int
+	// 不压缩的Content-Type（前缀匹配），为nil时使用默认的图片、音视频、压缩包等类型
+	ExcludedContentTypes []string
+	// 不压缩的请求路径（前缀匹配）
+	ExcludedPaths []string
+}
+
+const defaultCompressMinLength = 1024
+
+var defaultExcludedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+}
+
+// 压缩器，gzip.Writer和zlib.Writer都满足
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// 解析Accept-Encoding，返回各编码的q值，*对应未列出的编码
+func parseAcceptEncoding(accept string) map[string]float64 {
+	qs := make(map[string]float64)
+	for _, item := range strings.Split(accept, ",") {
+		coding, q := strings.TrimSpace(item), 1.0
+		if i := strings.Index(coding, ";"); i >= 0 {
+			param := strings.TrimSpace(coding[i+1:])
+			coding = strings.TrimSpace(coding[:i])
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if coding != "" {
+			qs[strings.ToLower(coding)] = q
+		}
+	}
+	return qs
+}
+
+// 客户端对编码的q值，未列出时使用*的q值，q<=0表示不接受
+func encodingQuality(qs map[string]float64, coding string) float64 {
+	if q, ok := qs[coding]; ok {
+		return q
+	}
+	return qs["*"]
+}
+
+// 根据Accept-Encoding选择编码，优先gzip，不支持时返回空字符串
+func negotiateEncoding(accept string) string {
+	qs := parseAcceptEncoding(accept)
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		if q := encodingQuality(qs, coding); q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// 压缩响应体，支持gzip和deflate
+func Compress() HandlerFunc {
+	return CompressWithConfig(CompressConfig{})
+}
+
+func CompressWithConfig(config CompressConfig) HandlerFunc {
+	level := gzip.DefaultCompression
+	if config.Level != nil {
+		level = *config.Level
+	}
+	if config.MinLength <= 0 {
+		config.MinLength = defaultCompressMinLength
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+	// 级别有误时NewWriterLevel返回nil，在创建中间件时检查，而不是在输出响应时panic
+	gzipWriter, err := gzip.NewWriterLevel(ioutil.Discard, level)
+	if err != nil {
+		panic("gen: invalid Compress level " + strconv.Itoa(level))
+	}
+	zlibWriter, err := zlib.NewWriterLevel(ioutil.Discard, level)
+	if err != nil {
+		panic("gen: invalid Compress level " + strconv.Itoa(level))
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(ioutil.Discard, level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(ioutil.Discard, level)
+			return w
+		}},
+	}
+	pools["gzip"].Put(gzipWriter)
+	pools["deflate"].Put(zlibWriter)
+
+	return func(c *Context) {
+		for _, prefix := range config.ExcludedPaths {
+			if strings.HasPrefix(c.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+		// 响应内容随Accept-Encoding变化，缓存需区分
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"))
+		// Range请求的偏移基于原始内容，不能压缩
+		if encoding == "" || c.Method == http.MethodHead || c.Req.Header.Get("Range") != "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			config:         &config,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			status:         http.StatusOK,
+		}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// 先缓存响应，达到MinLength后再决定是否压缩
+type compressWriter struct {
+	ResponseWriter
+	config   *CompressConfig
+	encoding string
+	pool     *sync.Pool
+
+	status      int
+	wroteHeader bool
+	size        int
+	buf         []byte
+	decided     bool
+	writer      compressor // 为nil表示不压缩
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader || code < http.StatusOK { // 忽略1xx
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.size += len(data)
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinLength {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// 根据状态码、Content-Type和长度决定是否压缩，并发送header及已缓存的内容
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// 压缩后无法再根据内容推断类型
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.shouldCompress() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.writer = w.pool.Get().(compressor)
+		w.writer.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.writer != nil {
+		_, err := w.writer.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if len(w.buf) < w.config.MinLength {
+		return false
+	}
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" { // 已经压缩过，例如预压缩的静态文件
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	for _, excluded := range w.config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// 支持流式输出：强制发送已缓存内容并刷新压缩器
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		w.decide()
+	}
+	if w.writer != nil {
+		w.writer.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// 请求结束时调用，发送剩余内容并归还压缩器
+func (w *compressWriter) close() {
+	if !w.decided && w.wroteHeader {
+		w.decide()
+	}
+	if w.writer != nil {
+		w.writer.Close()
+		w.writer.Reset(ioutil.Discard)
+		w.pool.Put(w.writer)
+		w.writer = nil
+	}
+}
+
+func (w *compressWriter) Status() int {
+	return w.status
+}
+
+func (w *compressWriter) Size() int {
+	return w.size
+}
+
+func (w *compressWriter) Written() bool {
+	return w.wroteHeader
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
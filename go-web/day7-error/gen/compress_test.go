@@ -0,0 +1,113 @@
+package gen
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"gzip, deflate":             "gzip",
+		"deflate;q=1, gzip;q=0.5":   "deflate",
+		"br":                        "",
+		"gzip;q=0, *":               "deflate",
+		"gzip;q=0":                  "",
+		"gzip;q=0, deflate;q=0":     "",
+		"*;q=0":                     "",
+		"deflate, *;q=0.5":          "deflate",
+		"*":                         "gzip",
+		"identity, deflate;q=0.001": "deflate",
+	}
+	for accept, want := range cases {
+		if got := negotiateEncoding(accept); got != want {
+			t.Fatalf("negotiateEncoding(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	engine := New()
+	engine.Use(Compress())
+	payload := strings.Repeat("lovecucu", 512)
+	engine.GET("/large", func(c *Context) {
+		c.JSON(http.StatusOK, H{"data": payload})
+	})
+	engine.GET("/small", func(c *Context) {
+		c.String(http.StatusOK, "small")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	engine.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("large response should be gzipped, got %v", w.Header())
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(reader)
+	if !strings.Contains(string(body), payload) {
+		t.Fatal("decompressed body should contain payload")
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	engine.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Fatal("small response should not be compressed")
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	engine := New()
+	engine.Use(Compress())
+	engine.GET("/stream", func(c *Context) {
+		c.Writer.Write([]byte("chunk"))
+		c.Writer.Flush()
+		if !c.Writer.Written() {
+			t.Fatal("header should be written after flush")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	engine.ServeHTTP(w, req)
+	if !w.Flushed || w.Body.String() != "chunk" {
+		t.Fatalf("flushed chunk should be sent, got %q", w.Body.String())
+	}
+}
+
+func TestCompressLevel(t *testing.T) {
+	level := gzip.NoCompression
+	engine := New()
+	engine.Use(CompressWithConfig(CompressConfig{Level: &level}))
+	payload := strings.Repeat("lovecucu", 512)
+	engine.GET("/", func(c *Context) {
+		c.String(http.StatusOK, payload)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	// 不压缩时数据原样存储，长度不会小于原始内容
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Body.Len() < len(payload) {
+		t.Fatalf("NoCompression level should be used, got %d bytes %v", w.Body.Len(), w.Header())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("invalid level should panic")
+		}
+	}()
+	invalid := 42
+	CompressWithConfig(CompressConfig{Level: &invalid})
+}
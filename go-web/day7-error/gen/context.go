@@ -1,9 +1,15 @@
 package gen
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html/template"
+	"math"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 /**
@@ -11,9 +17,12 @@ import (
 */
 type H map[string]interface{}
 
+// Abort后index的取值，保证后续的handler都不会再执行
+const abortIndex = math.MaxInt16
+
 type Context struct {
 	// 请求和响应实体
-	Writer http.ResponseWriter
+	Writer ResponseWriter
 	Req    *http.Request
 	// 请求路径、请求方法
 	Path   string
@@ -22,18 +31,70 @@ type Context struct {
 	Params map[string]string
 	// 响应状态码
 	StatusCode int
-	handlers   []HandlerFunc
-	index      int
-	engine     *Engine
+	// 匹配到的路由，例如/hello/:name
+	fullPath string
+	handlers []HandlerFunc
+	index    int
+	engine   *Engine
+	// 中间件与handler间共享的数据，clone后仍指向同一份
+	keys   map[string]interface{}
+	keysMu *sync.RWMutex
+	// 按请求替换的模板函数，例如csrfField
+	templateFuncs template.FuncMap
+	// 匹配到的group使用的模板
+	htmlRender HTMLRender
+	// JSON、Data、HTML输出时生成ETag并处理If-None-Match，见EnableETag
+	autoETag bool
+	weakETag bool
+	// 表单是否已解析及解析的错误，见ParseForm
+	formParsed bool
+	formErr    error
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
 	return &Context{
-		Writer: w,
+		Writer: newResponseWriter(w),
 		Req:    req,
 		Path:   req.URL.Path,
 		Method: req.Method,
 		index:  -1,
+		keys:   make(map[string]interface{}),
+		keysMu: &sync.RWMutex{},
+	}
+}
+
+// 创建绑定到engine的Context，handlers为依次执行的中间件及handler，调用Next开始执行，
+// 用于单独测试handler或中间件
+func (engine *Engine) CreateContext(w http.ResponseWriter, req *http.Request, handlers ...HandlerFunc) *Context {
+	c := newContext(w, req)
+	c.engine = engine
+	c.handlers = handlers
+	c.htmlRender = engine.htmlRender
+	return c
+}
+
+// 复制一份Context，供其他goroutine继续执行后续的handler
+func (c *Context) clone() *Context {
+	return &Context{
+		Writer:     c.Writer,
+		Req:        c.Req,
+		Path:       c.Path,
+		Method:     c.Method,
+		Params:     c.Params,
+		StatusCode: c.StatusCode,
+		fullPath:   c.fullPath,
+		handlers:   c.handlers,
+		index:      c.index,
+		engine:     c.engine,
+		keys:       c.keys,
+		keysMu:     c.keysMu,
+
+		templateFuncs: c.templateFuncs,
+		htmlRender:    c.htmlRender,
+		autoETag:      c.autoETag,
+		weakETag:      c.weakETag,
+		formParsed:    c.formParsed,
+		formErr:       c.formErr,
 	}
 }
 
@@ -45,20 +106,123 @@ func (c *Context) Next() {
 	}
 }
 
+// 终止后续中间件及handler的执行
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// 终止执行并返回指定状态码
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.Status(code)
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers) //
+	c.Abort()
 	c.JSON(code, H{"message": err})
 }
 
+// 保存数据，供后续的中间件或handler使用
+func (c *Context) Set(key string, value interface{}) {
+	c.keysMu.Lock()
+	c.keys[key] = value
+	c.keysMu.Unlock()
+}
+
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.keysMu.RLock()
+	value, exists = c.keys[key]
+	c.keysMu.RUnlock()
+	return
+}
+
+// 获取字符串类型的数据，不存在或类型不符时返回空字符串
+func (c *Context) GetString(key string) string {
+	if value, ok := c.Get(key); ok {
+		s, _ := value.(string)
+		return s
+	}
+	return ""
+}
+
+// 匹配到的路由，未匹配时为空字符串
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// 设置仅对本次请求生效的模板函数，name需已在LoadHTMLGlob时注册
+func (c *Context) setTemplateFunc(name string, fn interface{}) {
+	if c.templateFuncs == nil {
+		c.templateFuncs = template.FuncMap{}
+	}
+	c.templateFuncs[name] = fn
+}
+
 func (c *Context) Param(key string) string {
 	value := c.Params[key]
 	return value
 }
 
+// 获取表单参数，解析失败时返回空字符串，不会输出响应；
+// 需要区分请求体过大等错误时先调用ParseForm
 func (c *Context) PostForm(key string) string {
+	if c.parseForm() != nil {
+		return ""
+	}
 	return c.Req.FormValue(key)
 }
 
+// 解析表单，结果保存在Context中，失败后再次解析也会返回同样的错误
+func (c *Context) parseForm() error {
+	if !c.formParsed {
+		c.formParsed = true
+		// 非multipart请求时ParseMultipartForm不返回ParseForm的错误，因此分开调用
+		c.formErr = c.Req.ParseForm()
+		if c.formErr == nil {
+			c.formErr = c.Req.ParseMultipartForm(defaultMultipartMemory)
+			if c.formErr == http.ErrNotMultipart {
+				c.formErr = nil
+			}
+		}
+	}
+	return c.formErr
+}
+
+// 获取客户端IP，仅当请求来自可信代理时才使用X-Forwarded-For/X-Real-IP
+func (c *Context) ClientIP() string {
+	remoteIP, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		remoteIP = strings.TrimSpace(c.Req.RemoteAddr)
+	}
+	ip := net.ParseIP(remoteIP)
+	if ip == nil || c.engine == nil || !c.engine.isTrustedProxy(ip) {
+		return remoteIP
+	}
+
+	// 从右往左找到第一个不可信的地址，即真实的客户端
+	if forwarded := c.Req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		items := strings.Split(forwarded, ",")
+		for i := len(items) - 1; i >= 0; i-- {
+			item := strings.TrimSpace(items[i])
+			itemIP := net.ParseIP(item)
+			if itemIP == nil {
+				break
+			}
+			if i == 0 || !c.engine.isTrustedProxy(itemIP) {
+				return item
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remoteIP
+}
+
 func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
@@ -80,6 +244,16 @@ func (c *Context) String(code int, format string, values ...interface{}) {
 
 func (c *Context) JSON(code int, obj interface{}) {
 	c.SetHeader("Content-Type", "application/json")
+	if c.autoETag {
+		// 需要完整的响应体计算ETag
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(obj); err != nil {
+			http.Error(c.Writer, err.Error(), 500)
+			return
+		}
+		c.writeBody(code, buf.Bytes())
+		return
+	}
 	c.Status(code)
 	encoder := json.NewEncoder(c.Writer)
 	if err := encoder.Encode(obj); err != nil {
@@ -88,14 +262,45 @@ func (c *Context) JSON(code int, obj interface{}) {
 }
 
 func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	c.Writer.Write(data)
+	c.writeBody(code, data)
+}
+
+// HTTP/2 server push，仅在TLS下的HTTP/2连接可用，否则返回http.ErrNotSupported
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	if p := findPusher(c.Writer); p != nil {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// 沿Unwrap查找支持server push的ResponseWriter，Compress、ETag等中间件会替换c.Writer
+func findPusher(w http.ResponseWriter) http.Pusher {
+	for w != nil {
+		if p, ok := w.(http.Pusher); ok {
+			return p
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
 }
 
+// 渲染模板，先写入缓冲区，出错时返回500而不是半个页面
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmpTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(500, err.Error())
+	if c.htmlRender == nil {
+		c.Fail(http.StatusInternalServerError, "gen: no HTML templates loaded")
+		return
+	}
+	buf := htmlBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer htmlBufferPool.Put(buf)
+	if err := c.htmlRender.Render(buf, name, data, c.templateFuncs); err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+		return
 	}
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.writeBody(code, buf.Bytes())
 }
@@ -0,0 +1,127 @@
+package gen
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS中间件的配置
+type CORSConfig struct {
+	// 允许的来源，支持精确匹配、"*"以及"https://*.example.com"形式的子域名通配
+	AllowOrigins []string
+	// 自定义来源判断，与AllowOrigins任一满足即可
+	AllowOriginFunc func(origin string) bool
+	// 允许的方法，默认GET、POST、PUT、PATCH、DELETE、HEAD
+	AllowMethods []string
+	// 允许的请求头，为空时回显预检请求的Access-Control-Request-Headers
+	AllowHeaders []string
+	// 允许浏览器读取的响应头
+	ExposeHeaders []string
+	// 是否允许携带cookie等凭证，不能与"*"同时使用，需指定来源或使用AllowOriginFunc
+	AllowCredentials bool
+	// 预检结果的缓存时间
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}
+
+// 判断origin是否被允许
+func (config *CORSConfig) allowOrigin(origin string) bool {
+	for _, allowed := range config.AllowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		// 子域名通配，例如https://*.example.com
+		if i := strings.Index(allowed, "*"); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+}
+
+// 是否允许任意来源
+func (config *CORSConfig) allowAll() bool {
+	for _, allowed := range config.AllowOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// 跨域中间件，需注册在Engine或RouterGroup上，
+// OPTIONS预检请求会在路由匹配前直接返回，因此只注册了GET/POST的路由同样可用
+func CORS(config CORSConfig) HandlerFunc {
+	// 回显任意origin并允许凭证，相当于任何网站都能以用户身份发起请求
+	if config.AllowCredentials && config.allowAll() {
+		panic(`gen: CORS AllowCredentials cannot be used with AllowOrigins "*"`)
+	}
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge / time.Second))
+	// 允许任意来源时可以直接返回*，否则需要回显origin
+	wildcard := config.allowAll()
+
+	return func(c *Context) {
+		origin := c.Req.Header.Get("Origin")
+		preflight := c.Method == http.MethodOptions && c.Req.Header.Get("Access-Control-Request-Method") != ""
+		header := c.Writer.Header()
+		if !wildcard {
+			header.Add("Vary", "Origin")
+		}
+		if origin == "" { // 非跨域请求
+			c.Next()
+			return
+		}
+
+		if !config.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// 不返回CORS头，由浏览器拦截
+			c.Next()
+			return
+		}
+
+		if wildcard {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,82 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSEngine() *Engine {
+	engine := New()
+	engine.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://lovecucu.com", "https://*.example.com"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	engine.GET("/items", func(c *Context) {
+		c.String(http.StatusOK, "items")
+	})
+	return engine
+}
+
+func TestCORSPreflight(t *testing.T) {
+	engine := newCORSEngine()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	req.Header.Set("Origin", "https://api.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight should return 204, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://api.example.com" {
+		t.Fatal("origin should be echoed")
+	}
+	if w.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" {
+		t.Fatal("allow headers should be set")
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "3600" {
+		t.Fatal("max age should be set")
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	engine := newCORSEngine()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	req.Header.Set("Origin", "https://example.com.evil.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed preflight should return 403, got %d", w.Code)
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	engine := newCORSEngine()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/items", nil)
+	req.Header.Set("Origin", "https://lovecucu.com")
+	engine.ServeHTTP(w, req)
+
+	if w.Body.String() != "items" {
+		t.Fatal("handler should be executed")
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "X-Total" {
+		t.Fatalf("cors headers should be set, got %v", w.Header())
+	}
+}
+
+func TestCORSWildcardCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("AllowCredentials with * should panic")
+		}
+	}()
+	CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
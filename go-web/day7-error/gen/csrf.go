@@ -0,0 +1,195 @@
+package gen
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 保存在Context中的key
+const csrfSecretKey = "gen.csrfSecret"
+
+const csrfTokenLength = 32
+
+// CSRF中间件的配置
+type CSRFConfig struct {
+	// 对cookie签名的密钥，为空时启动时随机生成（多实例部署或重启后旧token会失效）
+	Key []byte
+	// 保存token的cookie，默认_csrf
+	CookieName   string
+	CookiePath   string
+	CookieDomain string
+	CookieSecure bool
+	// 默认http.SameSiteLaxMode
+	CookieSameSite http.SameSite
+	// cookie的有效期，默认12小时
+	MaxAge time.Duration
+	// 表单字段名，默认_csrf
+	FieldName string
+	// 请求头名，默认X-CSRF-Token
+	HeaderName string
+	// 不做校验的路径（前缀匹配），例如使用token认证的API分组
+	ExemptPaths []string
+	// 返回true时不做校验
+	Skipper func(c *Context) bool
+	// 校验失败时的处理，默认返回403
+	ErrorHandler HandlerFunc
+}
+
+// 不会修改数据的请求方法无需校验
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// cookie的值为secret.签名
+func signCSRFSecret(key []byte, secret []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(secret)
+	return base64.RawURLEncoding.EncodeToString(secret) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verifyCSRFCookie(key []byte, value string) []byte {
+	i := strings.Index(value, ".")
+	if i < 0 {
+		return nil
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil || len(secret) != csrfTokenLength {
+		return nil
+	}
+	if !hmac.Equal([]byte(signCSRFSecret(key, secret)), []byte(value)) {
+		return nil
+	}
+	return secret
+}
+
+// 每次生成的token都使用随机的pad异或，避免BREACH攻击
+func maskCSRFSecret(secret []byte) string {
+	token := make([]byte, 2*csrfTokenLength)
+	pad := token[:csrfTokenLength]
+	rand.Read(pad)
+	for i := range secret {
+		token[csrfTokenLength+i] = pad[i] ^ secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func unmaskCSRFToken(token string) []byte {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 2*csrfTokenLength {
+		return nil
+	}
+	secret := make([]byte, csrfTokenLength)
+	for i := range secret {
+		secret[i] = raw[i] ^ raw[csrfTokenLength+i]
+	}
+	return secret
+}
+
+// 获取本次请求的CSRF token，用于表单或AJAX请求头；未使用CSRF中间件时为空
+func (c *Context) CSRFToken() string {
+	value, ok := c.Get(csrfSecretKey)
+	if !ok {
+		return ""
+	}
+	return maskCSRFSecret(value.([]byte))
+}
+
+// CSRF防护中间件：cookie中保存签名后的secret，非安全方法需通过表单字段或请求头提交token，
+// 模板中可以使用{{ csrfField }}输出隐藏字段，或{{ csrfToken }}获取token
+func CSRF(config CSRFConfig) HandlerFunc {
+	if len(config.Key) == 0 {
+		config.Key = make([]byte, 32)
+		rand.Read(config.Key)
+	}
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.CookieSameSite == 0 {
+		config.CookieSameSite = http.SameSiteLaxMode
+	}
+	if config.MaxAge == 0 {
+		config.MaxAge = 12 * time.Hour
+	}
+	if config.FieldName == "" {
+		config.FieldName = "_csrf"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = func(c *Context) {
+			c.Fail(http.StatusForbidden, "invalid CSRF token")
+		}
+	}
+
+	return func(c *Context) {
+		for _, prefix := range config.ExemptPaths {
+			if strings.HasPrefix(c.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+		if config.Skipper != nil && config.Skipper(c) {
+			c.Next()
+			return
+		}
+
+		var secret []byte
+		if cookie, err := c.Req.Cookie(config.CookieName); err == nil {
+			secret = verifyCSRFCookie(config.Key, cookie.Value)
+		}
+		if secret == nil {
+			secret = make([]byte, csrfTokenLength)
+			rand.Read(secret)
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     config.CookieName,
+				Value:    signCSRFSecret(config.Key, secret),
+				Path:     config.CookiePath,
+				Domain:   config.CookieDomain,
+				MaxAge:   int(config.MaxAge / time.Second),
+				Secure:   config.CookieSecure,
+				HttpOnly: true,
+				SameSite: config.CookieSameSite,
+			})
+		}
+		c.Set(csrfSecretKey, secret)
+		c.Writer.Header().Add("Vary", "Cookie")
+		c.setTemplateFunc("csrfToken", c.CSRFToken)
+		c.setTemplateFunc("csrfField", func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(config.FieldName) +
+				`" value="` + c.CSRFToken() + `">`)
+		})
+
+		if !isSafeMethod(c.Method) {
+			token := c.Req.Header.Get(config.HeaderName)
+			if token == "" {
+				// 请求体过大等错误由ParseForm返回413或400
+				if c.ParseForm() != nil {
+					return
+				}
+				token = c.PostForm(config.FieldName)
+			}
+			submitted := unmaskCSRFToken(token)
+			if submitted == nil || subtle.ConstantTimeCompare(submitted, secret) != 1 {
+				c.Abort()
+				config.ErrorHandler(c)
+				return
+			}
+		}
+		c.Next()
+	}
+}
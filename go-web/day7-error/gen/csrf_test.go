@@ -0,0 +1,103 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCSRF(t *testing.T) {
+	engine := New()
+	engine.Use(CSRF(CSRFConfig{
+		Key:         []byte("secret"),
+		ExemptPaths: []string{"/api/"},
+		Skipper: func(c *Context) bool {
+			return c.Req.Header.Get("X-Skip") != ""
+		},
+	}))
+	engine.LoadHTMLFS(fstest.MapFS{"form.tmpl": {Data: []byte(`<form>{{ csrfField }}</form>`)}}, "*.tmpl")
+	engine.GET("/form", func(c *Context) {
+		c.HTML(http.StatusOK, "form.tmpl", nil)
+	})
+	engine.GET("/token", func(c *Context) {
+		c.String(http.StatusOK, c.CSRFToken())
+	})
+	for _, path := range []string{"/submit", "/api/submit"} {
+		engine.POST(path, func(c *Context) {
+			c.String(http.StatusOK, "ok")
+		})
+	}
+
+	do := func(req *http.Request, cookie *http.Cookie) *httptest.ResponseRecorder {
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	postForm := func(form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/submit", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return do(req, cookie)
+	}
+
+	// GET签发cookie，页面中输出隐藏字段
+	w := do(httptest.NewRequest("GET", "/form", nil), nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" || !cookies[0].HttpOnly {
+		t.Fatalf("GET should issue the csrf cookie, got %v", cookies)
+	}
+	cookie := cookies[0]
+	match := regexp.MustCompile(`^<form><input type="hidden" name="_csrf" value="([\w-]+)"></form>$`).FindStringSubmatch(w.Body.String())
+	if match == nil {
+		t.Fatalf("csrfField should render a hidden input, got %q", w.Body.String())
+	}
+
+	// 已有合法cookie时不再签发，每次生成的token不同
+	w = do(httptest.NewRequest("GET", "/token", nil), cookie)
+	if len(w.Result().Cookies()) != 0 || w.Body.String() == match[1] {
+		t.Fatalf("valid cookie should be kept and token masked, got %v %q", w.Result().Cookies(), w.Body.String())
+	}
+	headerToken := w.Body.String()
+
+	if w := postForm(url.Values{"_csrf": {match[1]}}, cookie); w.Code != http.StatusOK {
+		t.Fatalf("form token should pass, got %d", w.Code)
+	}
+	req := httptest.NewRequest("POST", "/submit", nil)
+	req.Header.Set("X-CSRF-Token", headerToken)
+	if w := do(req, cookie); w.Code != http.StatusOK {
+		t.Fatalf("header token should pass, got %d", w.Code)
+	}
+
+	if w := postForm(url.Values{}, cookie); w.Code != http.StatusForbidden {
+		t.Fatalf("missing token should return 403, got %d", w.Code)
+	}
+	if w := postForm(url.Values{"_csrf": {"bad"}}, cookie); w.Code != http.StatusForbidden {
+		t.Fatalf("bad token should return 403, got %d", w.Code)
+	}
+	// 其他cookie签发的token不能通过
+	other := do(httptest.NewRequest("GET", "/token", nil), nil)
+	if w := postForm(url.Values{"_csrf": {other.Body.String()}}, cookie); w.Code != http.StatusForbidden {
+		t.Fatalf("token of another secret should return 403, got %d", w.Code)
+	}
+
+	// 篡改的cookie签名校验失败，重新签发cookie并拒绝请求
+	tampered := &http.Cookie{Name: "_csrf", Value: cookie.Value[:len(cookie.Value)-2] + "xx"}
+	if w := postForm(url.Values{"_csrf": {match[1]}}, tampered); w.Code != http.StatusForbidden || len(w.Result().Cookies()) != 1 {
+		t.Fatalf("tampered cookie should return 403 with a new cookie, got %d %v", w.Code, w.Result().Cookies())
+	}
+
+	if w := do(httptest.NewRequest("POST", "/api/submit", nil), nil); w.Code != http.StatusOK {
+		t.Fatalf("exempt path should pass, got %d", w.Code)
+	}
+	req = httptest.NewRequest("POST", "/submit", nil)
+	req.Header.Set("X-Skip", "1")
+	if w := do(req, nil); w.Code != http.StatusOK {
+		t.Fatalf("skipped request should pass, got %d", w.Code)
+	}
+}
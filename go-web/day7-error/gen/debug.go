@@ -0,0 +1,139 @@
+package gen
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// 在group下注册调试相关的路由，middlewares会加在每个路由上，例如BasicAuth：
+//
+//	GET  /pprof/         pprof首页及各类profile
+//	GET  /vars           expvar
+//	GET  /runtime        Go版本、goroutine数、内存等运行时信息
+//	GET  /build          debug.ReadBuildInfo的构建信息
+//	GET  /routes         路由表
+//	GET  /gc             GC统计
+func RegisterDebug(group *RouterGroup, middlewares ...HandlerFunc) {
+	handle := func(method string, pattern string, handler HandlerFunc) {
+		handlers := append(append([]HandlerFunc{}, middlewares...), handler)
+		if method == http.MethodPost {
+			group.POST(pattern, handlers...)
+		} else {
+			group.GET(pattern, handlers...)
+		}
+	}
+
+	handle(http.MethodGet, "/pprof", pprofIndex)
+	handle(http.MethodGet, "/pprof/*name", pprofHandler)
+	handle(http.MethodPost, "/pprof/symbol", WrapH(http.HandlerFunc(pprof.Symbol)))
+	handle(http.MethodGet, "/vars", WrapH(expvar.Handler()))
+	handle(http.MethodGet, "/runtime", debugRuntime)
+	handle(http.MethodGet, "/build", debugBuild)
+	handle(http.MethodGet, "/routes", debugRoutes)
+	handle(http.MethodGet, "/gc", debugGC)
+}
+
+// 首页中的链接是相对路径，需以/结尾
+func pprofIndex(c *Context) {
+	if !strings.HasSuffix(c.Req.URL.Path, "/") {
+		http.Redirect(c.Writer, c.Req, c.Req.URL.Path+"/", http.StatusMovedPermanently)
+		return
+	}
+	pprof.Index(c.Writer, c.Req)
+}
+
+// pprof.Index依赖/debug/pprof/前缀，group可能挂在其他路径下，因此按名称分发
+func pprofHandler(c *Context) {
+	var h http.Handler
+	switch name := c.Param("name"); name {
+	case "cmdline":
+		h = http.HandlerFunc(pprof.Cmdline)
+	case "profile":
+		h = http.HandlerFunc(pprof.Profile)
+	case "symbol":
+		h = http.HandlerFunc(pprof.Symbol)
+	case "trace":
+		h = http.HandlerFunc(pprof.Trace)
+	default:
+		h = pprof.Handler(name)
+	}
+	h.ServeHTTP(c.Writer, c.Req)
+}
+
+func debugRuntime(c *Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	c.JSON(http.StatusOK, H{
+		"goVersion":    runtime.Version(),
+		"goos":         runtime.GOOS,
+		"goarch":       runtime.GOARCH,
+		"numCPU":       runtime.NumCPU(),
+		"gomaxprocs":   runtime.GOMAXPROCS(0),
+		"numGoroutine": runtime.NumGoroutine(),
+		"memory": H{
+			"alloc":       mem.Alloc,
+			"totalAlloc":  mem.TotalAlloc,
+			"sys":         mem.Sys,
+			"heapAlloc":   mem.HeapAlloc,
+			"heapInuse":   mem.HeapInuse,
+			"heapIdle":    mem.HeapIdle,
+			"heapObjects": mem.HeapObjects,
+			"stackInuse":  mem.StackInuse,
+		},
+	})
+}
+
+func debugBuild(c *Context) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		c.Fail(http.StatusNotFound, "build info not available")
+		return
+	}
+	deps := make([]H, 0, len(info.Deps))
+	for _, dep := range info.Deps {
+		item := H{"path": dep.Path, "version": dep.Version, "sum": dep.Sum}
+		if dep.Replace != nil {
+			item["replace"] = dep.Replace.Path + " " + dep.Replace.Version
+		}
+		deps = append(deps, item)
+	}
+	c.JSON(http.StatusOK, H{
+		"goVersion": runtime.Version(),
+		"path":      info.Path,
+		"main":      H{"path": info.Main.Path, "version": info.Main.Version, "sum": info.Main.Sum},
+		"deps":      deps,
+	})
+}
+
+func debugRoutes(c *Context) {
+	c.JSON(http.StatusOK, c.engine.Routes())
+}
+
+func debugGC(c *Context) {
+	stats := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&stats)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	c.JSON(http.StatusOK, H{
+		"numGC":          stats.NumGC,
+		"lastGC":         stats.LastGC,
+		"pauseTotal":     stats.PauseTotal.String(),
+		"pauseQuantiles": durationStrings(stats.PauseQuantiles), // 最小值、25%、50%、75%、最大值
+		"nextGC":         mem.NextGC,
+		"gcCPUFraction":  mem.GCCPUFraction,
+		"numForcedGC":    mem.NumForcedGC,
+	})
+}
+
+func durationStrings(durations []time.Duration) []string {
+	s := make([]string, len(durations))
+	for i, d := range durations {
+		s[i] = d.String()
+	}
+	return s
+}
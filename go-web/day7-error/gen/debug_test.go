@@ -0,0 +1,60 @@
+package gen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterDebug(t *testing.T) {
+	engine := New()
+	engine.GET("/hello/:name", func(c *Context) {})
+	RegisterDebug(engine.Group("/admin/debug"), BasicAuth(Accounts{"admin": "cucu"}))
+
+	get := func(path string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if auth {
+			req.SetBasicAuth("admin", "cucu")
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/admin/debug/routes", false); w.Code != http.StatusUnauthorized {
+		t.Fatalf("debug routes should require auth, got %d", w.Code)
+	}
+	if w := get("/admin/debug/pprof", true); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/admin/debug/pprof/" {
+		t.Fatalf("pprof index should redirect to trailing slash, got %d", w.Code)
+	}
+	if w := get("/admin/debug/pprof/", true); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Fatalf("unexpected pprof index %d", w.Code)
+	}
+	if w := get("/admin/debug/pprof/goroutine?debug=1", true); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Fatalf("unexpected goroutine profile %d", w.Code)
+	}
+	if w := get("/admin/debug/vars", true); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "memstats") {
+		t.Fatalf("unexpected expvar %d", w.Code)
+	}
+
+	w := get("/admin/debug/routes", true)
+	var routes []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, route := range routes {
+		found = found || (route.Method == "GET" && route.Path == "/hello/:name")
+	}
+	if !found {
+		t.Fatalf("routes should contain /hello/:name, got %v", routes)
+	}
+
+	for _, path := range []string{"/admin/debug/runtime", "/admin/debug/gc"} {
+		if w := get(path, true); w.Code != http.StatusOK {
+			t.Fatalf("%s returned %d", path, w.Code)
+		}
+	}
+}
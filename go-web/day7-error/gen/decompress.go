@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 请求体解压中间件的配置
+type DecompressConfig struct {
+	// 解压后请求体的最大字节数，防止zip炸弹，默认10MB
+	MaxSize int64
+}
+
+const defaultDecompressMaxSize = 10 << 20
+
+// 解压后的请求体，关闭时同时关闭解压器和原始body
+type decompressedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if e := b.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// 根据Content-Encoding解压请求体，之后PostForm等读取body的方法可直接使用
+func Decompress() HandlerFunc {
+	return DecompressWithConfig(DecompressConfig{})
+}
+
+func DecompressWithConfig(config DecompressConfig) HandlerFunc {
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultDecompressMaxSize
+	}
+
+	return func(c *Context) {
+		encoding := c.Req.Header.Get("Content-Encoding")
+		if encoding == "" || strings.EqualFold(encoding, "identity") || c.Req.Body == nil {
+			c.Next()
+			return
+		}
+
+		// 多个编码按应用顺序列出，解压时需逆序处理
+		codings := strings.Split(encoding, ",")
+		body := &decompressedBody{Reader: c.Req.Body, closers: []io.Closer{c.Req.Body}}
+		for i := len(codings) - 1; i >= 0; i-- {
+			var reader io.ReadCloser
+			var err error
+			switch strings.ToLower(strings.TrimSpace(codings[i])) {
+			case "gzip", "x-gzip":
+				reader, err = gzip.NewReader(body.Reader)
+			case "deflate":
+				reader, err = zlib.NewReader(body.Reader)
+			case "identity":
+				continue
+			default:
+				c.SetHeader("Accept-Encoding", "gzip, deflate")
+				c.Fail(http.StatusUnsupportedMediaType, "unsupported Content-Encoding: "+encoding)
+				return
+			}
+			if err != nil {
+				c.Fail(http.StatusBadRequest, "invalid request body: "+err.Error())
+				return
+			}
+			body.Reader = reader
+			body.closers = append(body.closers, reader)
+		}
+
+		c.Req.Body = http.MaxBytesReader(c.Writer, body, config.MaxSize)
+		c.Req.ContentLength = -1
+		c.Req.Header.Del("Content-Encoding")
+		c.Req.Header.Del("Content-Length")
+		c.Next()
+	}
+}
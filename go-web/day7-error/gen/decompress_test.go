@@ -0,0 +1,73 @@
+package gen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	engine := New()
+	engine.Use(DecompressWithConfig(DecompressConfig{MaxSize: 64}))
+	engine.POST("/form", func(c *Context) {
+		c.String(http.StatusOK, c.PostForm("name"))
+	})
+	engine.POST("/json", func(c *Context) {
+		var body map[string]string
+		if c.BindJSON(&body) == nil {
+			c.String(http.StatusOK, body["name"])
+		}
+	})
+
+	post := func(path string, contentType string, encoding string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return buf.Bytes()
+	}
+	deflated := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	form := "application/x-www-form-urlencoded"
+	if w := post("/form", form, "gzip", gzipped("name=cucu")); w.Code != http.StatusOK || w.Body.String() != "cucu" {
+		t.Fatalf("gzip form should be decompressed, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/form", form, "deflate", deflated("name=cucu")); w.Code != http.StatusOK || w.Body.String() != "cucu" {
+		t.Fatalf("deflate form should be decompressed, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/form", form, "", []byte("name=cucu")); w.Code != http.StatusOK || w.Body.String() != "cucu" {
+		t.Fatalf("plain form should pass through, got %d %s", w.Code, w.Body.String())
+	}
+
+	// 压缩后很小，解压后超出MaxSize
+	bomb := gzipped(`{"name":"` + strings.Repeat("a", 1024) + `"}`)
+	if w := post("/json", "application/json", "gzip", bomb); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("decompressed body over MaxSize should return 413, got %d", w.Code)
+	}
+
+	w := post("/form", form, "br", []byte("name=cucu"))
+	if w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Encoding") != "gzip, deflate" {
+		t.Fatalf("unsupported coding should return 415 with Accept-Encoding, got %d %v", w.Code, w.Header())
+	}
+	if w := post("/form", form, "gzip", []byte("not gzip")); w.Code != http.StatusBadRequest {
+		t.Fatalf("corrupt gzip should return 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,247 @@
+package gen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag中间件的配置
+type ETagConfig struct {
+	// 生成弱ETag（W/前缀），响应体会被压缩等改写时应使用弱ETag
+	Weak bool
+	// 返回资源当前的ETag，用于修改前校验If-Match，资源不存在时返回空字符串。
+	// 为nil时不校验，可在handler中调用c.CheckIfMatch
+	Current func(c *Context) string
+}
+
+// 根据响应体生成ETag，并处理If-None-Match、If-Modified-Since及If-Match，
+// 可用于Engine、RouterGroup或单个路由，对c.JSON、c.Data、c.HTML等所有输出生效
+func ETag() HandlerFunc {
+	return ETagWithConfig(ETagConfig{})
+}
+
+func ETagWithConfig(config ETagConfig) HandlerFunc {
+	return func(c *Context) {
+		if !isSafeMethod(c.Method) {
+			// 修改资源前，先确认客户端持有的是最新版本
+			if config.Current != nil && !c.CheckIfMatch(config.Current(c)) {
+				return
+			}
+			c.Next()
+			return
+		}
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &etagWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+			if p := recover(); p != nil {
+				// 丢弃已缓存的内容，由外层的Recovery返回500
+				if !w.passthrough {
+					w.ResponseWriter.Header().Del("ETag")
+				}
+				panic(p)
+			}
+			w.finish(c.Req, config.Weak)
+		}()
+		c.Next()
+	}
+}
+
+// 生成ETag
+func computeETag(h hash.Hash, weak bool) string {
+	tag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	if weak {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// 判断header中的ETag列表是否包含etag，weakCompare为false时为强比较，弱ETag不参与
+func matchETag(header string, etag string, weakCompare bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if !weakCompare && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if !weakCompare && strings.HasPrefix(item, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// 设置资源的ETag，未加引号时自动添加，之后c.JSON、c.Data、c.HTML输出时处理If-None-Match，
+// 例如以版本号作为ETag，无需根据响应体计算
+func (c *Context) SetETag(etag string) {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	c.SetHeader("ETag", etag)
+	c.autoETag = true
+}
+
+// 之后c.JSON、c.Data、c.HTML输出时根据响应体生成ETag，If-None-Match命中时返回304，
+// 只对单个响应生效，无需使用ETag中间件缓存整个响应
+func (c *Context) EnableETag(weak bool) {
+	c.autoETag = true
+	c.weakETag = weak
+}
+
+// 修改资源前校验If-Match，etag为资源当前的ETag，资源不存在时传空字符串。
+// 不匹配时返回412并返回false
+func (c *Context) CheckIfMatch(etag string) bool {
+	ifMatch := c.Req.Header.Get("If-Match")
+	if ifMatch == "" || matchETag(ifMatch, etag, false) {
+		return true
+	}
+	c.Fail(http.StatusPreconditionFailed, http.StatusText(http.StatusPreconditionFailed))
+	return false
+}
+
+// 根据If-None-Match判断客户端缓存是否有效，没有时使用If-Modified-Since与Last-Modified比较
+func isNotModified(req *http.Request, header http.Header, etag string) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return matchETag(ifNoneMatch, etag, true)
+	}
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		since, err1 := http.ParseTime(ims)
+		modified, err2 := http.ParseTime(header.Get("Last-Modified"))
+		return err1 == nil && err2 == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// 输出响应体，开启ETag时处理If-None-Match及If-Modified-Since
+func (c *Context) writeBody(code int, body []byte) {
+	if c.autoETag && code == http.StatusOK && (c.Method == http.MethodGet || c.Method == http.MethodHead) {
+		header := c.Writer.Header()
+		etag := header.Get("ETag")
+		if etag == "" {
+			h := sha256.New()
+			h.Write(body)
+			etag = computeETag(h, c.weakETag)
+			header.Set("ETag", etag)
+		}
+		if isNotModified(c.Req, header, etag) {
+			header.Del("Content-Type")
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Status(code)
+	c.Writer.Write(body)
+}
+
+// 缓存完整的响应以计算ETag，Flush后退化为直接输出
+type etagWriter struct {
+	ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	w.wroteHeader = true
+	return w.buf.Write(data)
+}
+
+// 流式输出无法计算ETag，发送已缓存的内容后直接输出
+func (w *etagWriter) Flush() {
+	if !w.passthrough {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *etagWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+func (w *etagWriter) Written() bool {
+	return w.passthrough || w.wroteHeader
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 计算ETag并处理条件请求，命中时返回304且不带body
+func (w *etagWriter) finish(req *http.Request, weak bool) {
+	if w.passthrough {
+		return
+	}
+	dst := w.ResponseWriter
+	if !w.wroteHeader {
+		return
+	}
+	if w.status != http.StatusOK {
+		dst.WriteHeader(w.status)
+		dst.Write(w.buf.Bytes())
+		return
+	}
+
+	header := dst.Header()
+	etag := header.Get("ETag")
+	if etag == "" {
+		h := sha256.New()
+		h.Write(w.buf.Bytes())
+		etag = computeETag(h, weak)
+		header.Set("ETag", etag)
+	}
+
+	if isNotModified(req, header, etag) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		dst.WriteHeader(http.StatusNotModified)
+		return
+	}
+	dst.WriteHeader(w.status)
+	dst.Write(w.buf.Bytes())
+}
@@ -0,0 +1,153 @@
+package gen
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	engine := New()
+	items := engine.Group("/items")
+	version, calls := "v1", 0
+	// 记录中间件执行的次数，校验If-Match不应再次执行整条handler链
+	items.Use(func(c *Context) {
+		calls++
+		c.Next()
+	})
+	items.Use(ETagWithConfig(ETagConfig{
+		Current: func(c *Context) string {
+			return `"` + version + `"`
+		},
+	}))
+	items.GET("/1", func(c *Context) {
+		c.SetETag(version)
+		c.JSON(http.StatusOK, H{"version": version})
+	})
+	items.POST("/1", func(c *Context) {
+		version = c.PostForm("version")
+		c.JSON(http.StatusOK, H{"version": version})
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/items/1", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != `"v1"` {
+		t.Fatalf("response should have ETag, got %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("matched If-None-Match should return 304 without body, got %d", w.Code)
+	}
+
+	calls = 0
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/items/1?version=v2", nil)
+	req.Header.Set("If-Match", etag)
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || version != "v2" || calls != 1 {
+		t.Fatalf("matched If-Match should update once, got %d %d", w.Code, calls)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/items/1?version=v3", nil)
+	req.Header.Set("If-Match", etag)
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed || version != "v2" {
+		t.Fatalf("stale If-Match should return 412, got %d", w.Code)
+	}
+}
+
+func TestContextETag(t *testing.T) {
+	engine := New()
+	engine.GET("/data", func(c *Context) {
+		c.EnableETag(true)
+		c.Data(http.StatusOK, []byte("data"))
+	})
+	engine.POST("/data", func(c *Context) {
+		if c.CheckIfMatch(`"v1"`) {
+			c.String(http.StatusOK, "updated")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/data", nil))
+	etag := w.Header().Get("ETag")
+	if w.Body.String() != "data" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("EnableETag should set a weak ETag, got %q %v", w.Body.String(), w.Header())
+	}
+	req := httptest.NewRequest("GET", "/data", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("matched If-None-Match should return 304, got %d", w.Code)
+	}
+
+	for ifMatch, code := range map[string]int{"": http.StatusOK, `"v1"`: http.StatusOK, "*": http.StatusOK, `"v0"`: http.StatusPreconditionFailed, `W/"v1"`: http.StatusPreconditionFailed} {
+		req := httptest.NewRequest("POST", "/data", nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Fatalf("If-Match %q should return %d, got %d", ifMatch, code, w.Code)
+		}
+	}
+}
+
+func TestETagPanic(t *testing.T) {
+	engine := New()
+	engine.Use(RecoveryWithConfig(RecoveryConfig{Output: ioutil.Discard}), ETag())
+	engine.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "partial") || w.Header().Get("ETag") != "" {
+		t.Fatalf("panic should discard the buffered response, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestETagIfModifiedSince(t *testing.T) {
+	modified := time.Date(2024, 5, 20, 13, 14, 0, 0, time.UTC)
+	lastModified := func(c *Context) {
+		c.SetHeader("Last-Modified", modified.Format(http.TimeFormat))
+	}
+	engine := New()
+	engine.GET("/middleware", ETag(), func(c *Context) {
+		lastModified(c)
+		c.String(http.StatusOK, "middleware")
+	})
+	engine.GET("/context", func(c *Context) {
+		lastModified(c)
+		c.EnableETag(false)
+		c.Data(http.StatusOK, []byte("context"))
+	})
+
+	for _, path := range []string{"/middleware", "/context"} {
+		for since, code := range map[time.Time]int{
+			modified:                   http.StatusNotModified,
+			modified.Add(time.Hour):    http.StatusNotModified,
+			modified.Add(-time.Second): http.StatusOK,
+		} {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("If-Modified-Since", since.Format(http.TimeFormat))
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != code {
+				t.Fatalf("%s with If-Modified-Since %v should return %d, got %d", path, since, code, w.Code)
+			}
+		}
+	}
+}
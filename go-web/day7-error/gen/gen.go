@@ -1,11 +1,15 @@
 package gen
 
 import (
+	"context"
+	"fmt"
 	"html/template"
-	"log"
+	"net"
 	"net/http"
-	"path"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 /**
@@ -13,6 +17,13 @@ import (
 */
 type HandlerFunc func(*Context)
 
+// 将http.Handler包装为HandlerFunc，例如pprof、expvar等标准库的handler
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
+
 type (
 	// 根据实际情况拆分，中间件是以group为维度，故放在这里
 	RouterGroup struct {
@@ -20,29 +31,45 @@ type (
 		middlewares []HandlerFunc
 		parent      *RouterGroup
 		engine      *Engine
+		htmlRender  HTMLRender // 为nil时使用上级group的
 	}
 
 	// 贯穿整个应用的结构体
 	Engine struct {
-		*RouterGroup  // 相当于继承RouterGroup
-		router        *router
-		groups        []*RouterGroup
-		htmpTemplates *template.Template
-		funcMap       template.FuncMap
+		*RouterGroup // 相当于继承RouterGroup
+		router       *router
+		groups       []*RouterGroup
+		funcMap      template.FuncMap
+		htmlReload   *bool // 模板文件变化时重新解析，为nil时debug模式下开启
+		// 使用StaticConfig.Fingerprint注册的静态资源
+		assets []*assetManifest
+		// 可信的代理，只有来自这些地址的请求才会读取X-Forwarded-For
+		trustedProxies []*net.IPNet
+
+		// 服务生命周期相关
+		options         serverOptions
+		mu              sync.Mutex
+		server          *server
+		onStart         []HookFunc
+		onShutdown      []HookFunc
+		shutdownTimeout time.Duration
 	}
 )
 
 // 实例Engine
-func New() *Engine {
+func New(opts ...Option) *Engine {
 	engine := &Engine{router: newRouter()}
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
+	for _, opt := range opts {
+		opt(engine)
+	}
 	return engine
 }
 
 // 实例化带Logger中间件的Engine
-func Default() *Engine {
-	engine := New()
+func Default(opts ...Option) *Engine {
+	engine := New(opts...)
 	engine.Use(Logger(), Recovery())
 	return engine
 }
@@ -59,11 +86,11 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	return newGroup
 }
 
-// 基于RouterGroup添加路由
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+// 基于RouterGroup添加路由，handlers中最后一个为业务处理，之前的为路由级中间件
+func (group *RouterGroup) addRoute(method string, comp string, handlers []HandlerFunc) {
 	pattern := group.prefix + comp
-	log.Printf("Router %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	debugPrintf("Router %4s - %s", method, pattern)
+	group.engine.router.addRoute(method, pattern, handlers)
 }
 
 // 注册中间件
@@ -71,64 +98,120 @@ func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-// 静态文件处理方法
-func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := path.Join(group.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(c *Context) {
-		file := c.Param("filepath")
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
-		fileServer.ServeHTTP(c.Writer, c.Req)
-	}
-}
-
-// 注册静态文件的路由
-func (group *RouterGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	// Register GET handlers
-	group.GET(urlPattern, handler)
-}
-
 // 设置GET类路由
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) {
+	group.addRoute("GET", pattern, handlers)
 }
 
 // 设置POST路由
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) {
+	group.addRoute("POST", pattern, handlers)
 }
 
-// 代理http，执行监听
+// 代理http，执行监听，收到SIGINT/SIGTERM时优雅退出
 func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+	return engine.RunContext(context.Background(), addr)
 }
 
 // 监听到请求时，执行的回调
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	var middlewares []HandlerFunc
+	var render HTMLRender
 	for _, group := range engine.groups {
 		// 这里直接要卖URL.Path是否包含group.prefix来判断是否使用这个group的中间件
 		if strings.HasPrefix(req.URL.Path, group.prefix) {
 			middlewares = append(middlewares, group.middlewares...)
+			// 子group在上级之后创建，因此最后一个设置了模板的即最近的
+			if group.htmlRender != nil {
+				render = group.htmlRender
+			}
 		}
 	}
 	c := newContext(w, req)
 	c.handlers = middlewares // 初始化中间件
+	c.htmlRender = render
 	c.engine = engine
 	engine.router.handle(c)
 }
 
+// 路由信息
+type RouteInfo struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Handlers int    `json:"handlers"` // 路由级中间件及handler的数量，不含group中间件
+}
+
+// 获取所有已注册的路由，按method和path排序
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0)
+	for method := range engine.router.roots {
+		for _, n := range engine.router.getRoutes(method) {
+			routes = append(routes, RouteInfo{
+				Method:   method,
+				Path:     n.pattern,
+				Handlers: len(engine.router.handlers[routeKey(method, n.pattern)]),
+			})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
+}
+
+// 设置可信代理，支持IP或CIDR，ClientIP只信任来自这些代理的X-Forwarded-For/X-Real-IP
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	trusted := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("gen: invalid proxy address %q", proxy)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		trusted = append(trusted, cidr)
+	}
+	engine.trustedProxies = trusted
+	return nil
+}
+
+// 判断ip是否为可信代理
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, cidr := range engine.trustedProxies {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // 设置自定义函数
 func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.funcMap = funcMap
 }
 
-// 加载模板
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmpTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// 内置的模板函数与自定义函数合并，内置函数在渲染时会按请求替换
+func (engine *Engine) templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"csrfField": func() template.HTML { return "" },
+		"csrfToken": func() string { return "" },
+		"cspNonce":  func() string { return "" },
+		"asset":     engine.AssetPath,
+	}
+	for name, fn := range engine.funcMap {
+		funcs[name] = fn
+	}
+	return funcs
 }
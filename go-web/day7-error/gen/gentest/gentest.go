@@ -0,0 +1,44 @@
+// gentest提供测试gen应用的辅助方法：创建测试用的Context、单独执行中间件，
+// 以及链式构造请求并校验响应：
+//
+//	gentest.New(engine).GET("/x").Header("X-Token", "t").Expect(t).Status(200).JSONPath("a.b", 1)
+package gentest
+
+import (
+	"gen"
+	"net/http"
+	"net/http/httptest"
+)
+
+// 创建GET /的Context及对应的Engine，可通过engine设置模板、可信代理等
+func CreateTestContext(w http.ResponseWriter) (*gen.Context, *gen.Engine) {
+	return CreateTestContextWithRequest(w, httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func CreateTestContextWithRequest(w http.ResponseWriter, req *http.Request) (*gen.Context, *gen.Engine) {
+	engine := gen.New()
+	return engine.CreateContext(w, req), engine
+}
+
+// 单独执行中间件的结果
+type MiddlewareResult struct {
+	*httptest.ResponseRecorder
+	Context *gen.Context
+	// 中间件是否调用了Next，执行到了后续的handler
+	Next bool
+}
+
+// 单独执行中间件，不经过路由；next为中间件之后执行的handler，为空时返回200
+func RunMiddleware(req *http.Request, middleware gen.HandlerFunc, next ...gen.HandlerFunc) *MiddlewareResult {
+	result := &MiddlewareResult{ResponseRecorder: httptest.NewRecorder()}
+	final := func(c *gen.Context) {
+		result.Next = true
+		if len(next) == 0 {
+			c.Status(http.StatusOK)
+		}
+	}
+	handlers := append([]gen.HandlerFunc{middleware, final}, next...)
+	result.Context = gen.New().CreateContext(result.ResponseRecorder, req, handlers...)
+	result.Context.Next()
+	return result
+}
@@ -0,0 +1,81 @@
+package gentest
+
+import (
+	"gen"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	gen.SetMode(gen.TestMode)
+	os.Exit(m.Run())
+}
+
+func TestCreateTestContext(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := CreateTestContext(w)
+	c.Params = map[string]string{"name": "cucu"}
+	func(c *gen.Context) {
+		c.JSON(http.StatusOK, gen.H{"name": c.Param("name")})
+	}(c)
+	if w.Code != http.StatusOK || w.Body.String() != "{\"name\":\"cucu\"}\n" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunMiddleware(t *testing.T) {
+	auth := gen.BasicAuth(gen.Accounts{"admin": "cucu"})
+
+	result := RunMiddleware(httptest.NewRequest("GET", "/", nil), auth)
+	if result.Next || result.Code != http.StatusUnauthorized {
+		t.Fatalf("request without credentials should be rejected, got %d", result.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "cucu")
+	result = RunMiddleware(req, auth, func(c *gen.Context) {
+		c.String(http.StatusOK, "%s", c.Principal())
+	})
+	if !result.Next || result.Body.String() != "admin" {
+		t.Fatalf("valid credentials should pass, got %d %s", result.Code, result.Body.String())
+	}
+}
+
+func TestClient(t *testing.T) {
+	engine := gen.New()
+	engine.GET("/users/:name", func(c *gen.Context) {
+		c.JSON(http.StatusOK, gen.H{
+			"user":  gen.H{"name": c.Param("name"), "age": 18},
+			"tags":  []string{"go", c.Query("tag")},
+			"token": c.Req.Header.Get("X-Token"),
+		})
+	})
+	engine.POST("/users", func(c *gen.Context) {
+		var body map[string]interface{}
+		if c.BindJSON(&body) == nil {
+			c.JSON(http.StatusCreated, body)
+		}
+	})
+
+	client := New(engine).Header("X-Token", "secret")
+	client.GET("/users/cucu").Query("tag", "web").Expect(t).
+		Status(http.StatusOK).
+		Header("Content-Type", "application/json").
+		JSONPath("user.name", "cucu").
+		JSONPath("user.age", 18).
+		JSONPath("tags.1", "web").
+		JSONPath("token", "secret")
+	// 请求的header覆盖默认值
+	client.GET("/users/cucu").Header("X-Token", "override").Expect(t).
+		JSONPath("token", "override")
+
+	client.POST("/users").JSON(gen.H{"name": "cucu", "age": 18}).Expect(t).
+		Status(http.StatusCreated).
+		JSON(map[string]interface{}{"name": "cucu", "age": 18})
+
+	client.POST("/users").Body("application/json", []byte("{")).Expect(t).
+		Status(http.StatusBadRequest).
+		BodyContains("invalid request body")
+}
@@ -0,0 +1,166 @@
+package gentest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// 向handler（通常为*gen.Engine）发送请求的客户端
+type Client struct {
+	handler http.Handler
+	header  http.Header
+}
+
+func New(handler http.Handler) *Client {
+	return &Client{handler: handler, header: make(http.Header)}
+}
+
+// 设置所有请求都带上的header
+func (client *Client) Header(key string, value string) *Client {
+	client.header.Set(key, value)
+	return client
+}
+
+func (client *Client) GET(path string) *Request {
+	return client.Request(http.MethodGet, path)
+}
+
+func (client *Client) POST(path string) *Request {
+	return client.Request(http.MethodPost, path)
+}
+
+func (client *Client) PUT(path string) *Request {
+	return client.Request(http.MethodPut, path)
+}
+
+func (client *Client) PATCH(path string) *Request {
+	return client.Request(http.MethodPatch, path)
+}
+
+func (client *Client) DELETE(path string) *Request {
+	return client.Request(http.MethodDelete, path)
+}
+
+func (client *Client) HEAD(path string) *Request {
+	return client.Request(http.MethodHead, path)
+}
+
+func (client *Client) Request(method string, path string) *Request {
+	return &Request{
+		client: client,
+		method: method,
+		path:   path,
+		header: client.header.Clone(),
+		query:  make(url.Values),
+	}
+}
+
+// 链式构造的请求，调用Expect或Do时发送
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    []byte
+	err     error // 构造请求时的错误，发送时报告
+}
+
+// 设置请求头，覆盖Client.Header设置的默认值
+func (r *Request) Header(key string, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) Query(key string, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) Cookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+func (r *Request) BasicAuth(username string, password string) *Request {
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(username, password)
+	r.header.Set("Authorization", req.Header.Get("Authorization"))
+	return r
+}
+
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// 将obj编码为JSON作为请求体
+func (r *Request) JSON(obj interface{}) *Request {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		r.err = err
+	}
+	return r.Body("application/json", body)
+}
+
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// 构造http.Request
+func (r *Request) HTTPRequest() (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req := httptest.NewRequest(r.method, target, body)
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	return req, nil
+}
+
+// 发送请求，构造请求出错时panic
+func (r *Request) Do() *httptest.ResponseRecorder {
+	req, err := r.HTTPRequest()
+	if err != nil {
+		panic(err)
+	}
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+	return w
+}
+
+// 发送请求并返回用于校验的响应
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	req, err := r.HTTPRequest()
+	if err != nil {
+		t.Fatalf("%s %s: build request: %v", r.method, r.path, err)
+	}
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+	return &Response{Recorder: w, t: t, name: r.method + " " + r.path}
+}
@@ -0,0 +1,135 @@
+package gentest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 链式校验响应，失败时通过t.Errorf报告并继续校验
+type Response struct {
+	Recorder *httptest.ResponseRecorder
+	t        testing.TB
+	name     string // 请求的方法和路径，用于错误信息
+}
+
+func (res *Response) Status(code int) *Response {
+	res.t.Helper()
+	if res.Recorder.Code != code {
+		res.t.Errorf("%s: status = %d, want %d, body: %s", res.name, res.Recorder.Code, code, res.Recorder.Body.String())
+	}
+	return res
+}
+
+func (res *Response) Header(key string, value string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Header().Get(key); got != value {
+		res.t.Errorf("%s: header %s = %q, want %q", res.name, key, got, value)
+	}
+	return res
+}
+
+func (res *Response) Body(body string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Body.String(); got != body {
+		res.t.Errorf("%s: body = %q, want %q", res.name, got, body)
+	}
+	return res
+}
+
+func (res *Response) BodyContains(substr string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Body.String(); !strings.Contains(got, substr) {
+		res.t.Errorf("%s: body %q does not contain %q", res.name, got, substr)
+	}
+	return res
+}
+
+// 校验整个JSON响应，want会先编码为JSON再比较，因此可以使用gen.H、结构体等
+func (res *Response) JSON(want interface{}) *Response {
+	res.t.Helper()
+	got, err := res.decode()
+	if err != nil {
+		res.t.Errorf("%s: %v", res.name, err)
+		return res
+	}
+	if !jsonEqual(got, want) {
+		res.t.Errorf("%s: json = %s, want %s", res.name, marshal(got), marshal(want))
+	}
+	return res
+}
+
+// 校验JSON中path对应的值，path以.分隔，数组使用下标，例如"data.items.0.name"
+func (res *Response) JSONPath(path string, want interface{}) *Response {
+	res.t.Helper()
+	doc, err := res.decode()
+	if err != nil {
+		res.t.Errorf("%s: %v", res.name, err)
+		return res
+	}
+	got, err := lookupJSONPath(doc, path)
+	if err != nil {
+		res.t.Errorf("%s: %v", res.name, err)
+		return res
+	}
+	if !jsonEqual(got, want) {
+		res.t.Errorf("%s: json path %s = %s, want %s", res.name, path, marshal(got), marshal(want))
+	}
+	return res
+}
+
+func (res *Response) decode() (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal(res.Recorder.Body.Bytes(), &doc); err != nil {
+		return nil, fmt.Errorf("invalid json body %q: %v", res.Recorder.Body.String(), err)
+	}
+	return doc, nil
+}
+
+func lookupJSONPath(doc interface{}, path string) (interface{}, error) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[key]
+			if !ok {
+				return nil, fmt.Errorf("json path %s: key %q not found", path, key)
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, fmt.Errorf("json path %s: invalid index %q", path, key)
+			}
+			current = value[i]
+		default:
+			return nil, fmt.Errorf("json path %s: %q is not an object or array", path, key)
+		}
+	}
+	return current, nil
+}
+
+// 将want编码后再解码，使1与1.0、结构体与map等可以比较
+func jsonEqual(got interface{}, want interface{}) bool {
+	data, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, normalized)
+}
+
+func marshal(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -1,3 +1,15 @@
 module gen
 
-go 1.14
+go 1.19
+
+require (
+	gencache v0.0.0
+	golang.org/x/net v0.35.0
+)
+
+require (
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/protobuf v1.26.0 // indirect
+)
+
+replace gencache => ../../../go-cache/day7-protobuf/gencache
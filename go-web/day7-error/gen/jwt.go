@@ -0,0 +1,156 @@
+package gen
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// JWT中间件的配置，Secret和PublicKey至少设置一个
+type JWTConfig struct {
+	// HS256的密钥
+	Secret []byte
+	// RS256的公钥
+	PublicKey *rsa.PublicKey
+	// 非空时校验iss
+	Issuer string
+	// 非空时校验aud
+	Audience string
+	// 校验exp/nbf时允许的时钟偏差
+	Leeway time.Duration
+	// WWW-Authenticate中的realm
+	Realm string
+
+	now func() time.Time
+}
+
+// JWT中的payload
+type JWTClaims map[string]interface{}
+
+// 获取字符串类型的claim
+func (claims JWTClaims) String(key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
+
+// 获取时间类型的claim（NumericDate）
+func (claims JWTClaims) Time(key string) (time.Time, bool) {
+	switch v := claims[key].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return time.Unix(n, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// aud可以是字符串或字符串数组
+func (claims JWTClaims) hasAudience(audience string) bool {
+	switch v := claims["aud"].(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	ErrTokenMalformed    = errors.New("token is malformed")
+	ErrTokenAlgorithm    = errors.New("token algorithm is not allowed")
+	ErrTokenSignature    = errors.New("token signature is invalid")
+	ErrTokenExpired      = errors.New("token is expired")
+	ErrTokenNotValidYet  = errors.New("token is not valid yet")
+	ErrTokenInvalidClaim = errors.New("token has invalid issuer or audience")
+)
+
+// 校验签名及exp/nbf/iss/aud，成功时返回claims
+func ParseJWT(token string, config JWTConfig) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	// 只接受已配置密钥对应的算法，防止alg混淆攻击
+	signingInput := parts[0] + "." + parts[1]
+	switch {
+	case header.Alg == "HS256" && len(config.Secret) > 0:
+		mac := hmac.New(sha256.New, config.Secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, ErrTokenSignature
+		}
+	case header.Alg == "RS256" && config.PublicKey != nil:
+		digest := sha256.Sum256([]byte(signingInput))
+		if rsa.VerifyPKCS1v15(config.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, ErrTokenSignature
+		}
+	default:
+		return nil, ErrTokenAlgorithm
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	claims := JWTClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	now := time.Now()
+	if config.now != nil {
+		now = config.now()
+	}
+	if exp, ok := claims.Time("exp"); ok && !now.Before(exp.Add(config.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(config.Leeway).Before(nbf) {
+		return nil, ErrTokenNotValidYet
+	}
+	if config.Issuer != "" && claims.String("iss") != config.Issuer {
+		return nil, ErrTokenInvalidClaim
+	}
+	if config.Audience != "" && !claims.hasAudience(config.Audience) {
+		return nil, ErrTokenInvalidClaim
+	}
+	return claims, nil
+}
+
+// JWT认证，支持HS256和RS256，通过后JWTClaims保存在AuthPrincipalKey
+func JWTAuth(config JWTConfig) HandlerFunc {
+	if len(config.Secret) == 0 && config.PublicKey == nil {
+		panic("gen: JWTAuth requires Secret or PublicKey")
+	}
+	if config.Realm == "" {
+		config.Realm = "Authorization Required"
+	}
+	return BearerAuthForRealm(func(token string) (interface{}, error) {
+		return ParseJWT(token, config)
+	}, config.Realm)
+}
@@ -1,9 +1,6 @@
 package gen
 
-import (
-	"log"
-	"time"
-)
+import "time"
 
 func Logger() HandlerFunc {
 	return func(c *Context) {
@@ -12,6 +9,6 @@ func Logger() HandlerFunc {
 		// Process request
 		c.Next()
 		// Calculate resolution time
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+		logPrintf("[%d] %s in %v%s", c.StatusCode, c.Req.RequestURI, time.Since(t), c.logIDs())
 	}
 }
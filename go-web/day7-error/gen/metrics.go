@@ -0,0 +1,202 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 未匹配到路由的请求统一使用的route标签，避免按原始路径产生大量时间序列
+const unmatchedRoute = "unmatched"
+
+// 非标准的请求方法统一使用的method标签
+const otherMethod = "other"
+
+// 标准的请求方法原样作为标签，其他方法由客户端任意指定，统一为other
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
+
+// 默认的延迟分桶，单位为秒
+var defaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// 指标的配置
+type MetricsConfig struct {
+	// 指标名的前缀，默认gen
+	Namespace string
+	// 请求延迟直方图的分桶上界（秒），需升序
+	Buckets []float64
+}
+
+// 单个method、route、status组合的请求统计
+type requestMetrics struct {
+	count   uint64
+	sum     float64
+	buckets []uint64 // 落在每个分桶内的请求数，输出时再累加
+}
+
+// 以Prometheus文本格式输出的HTTP指标，标签中的route为路由的pattern而非原始路径
+type MetricsRegistry struct {
+	config MetricsConfig
+
+	mu       sync.Mutex
+	requests map[string]*requestMetrics // 标签 -> 请求统计
+	inFlight map[string]int64           // 标签 -> 正在处理的请求数
+}
+
+// 默认的指标，由Metrics和MetricsHandler使用
+var DefaultMetrics = NewMetricsRegistry(MetricsConfig{})
+
+func NewMetricsRegistry(config MetricsConfig) *MetricsRegistry {
+	if config.Namespace == "" {
+		config.Namespace = "gen"
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = defaultMetricsBuckets
+	}
+	return &MetricsRegistry{
+		config:   config,
+		requests: make(map[string]*requestMetrics),
+		inFlight: make(map[string]int64),
+	}
+}
+
+// 记录请求数、延迟及正在处理的请求数到DefaultMetrics
+func Metrics() HandlerFunc {
+	return DefaultMetrics.Handler()
+}
+
+// 输出DefaultMetrics，例如r.GET("/metrics", gen.MetricsHandler())
+func MetricsHandler() HandlerFunc {
+	return DefaultMetrics.ServeMetrics
+}
+
+// 记录指标的中间件
+func (m *MetricsRegistry) Handler() HandlerFunc {
+	return func(c *Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := methodLabel(c.Method)
+		flightLabels := formatLabels("method", method, "route", route)
+		m.mu.Lock()
+		m.inFlight[flightLabels]++
+		m.mu.Unlock()
+
+		start := time.Now()
+		defer func() {
+			elapsed := time.Since(start).Seconds()
+			status := c.Writer.Status()
+			p := recover()
+			if p != nil {
+				status = http.StatusInternalServerError
+			}
+			labels := formatLabels("method", method, "route", route, "status", strconv.Itoa(status))
+			m.mu.Lock()
+			m.inFlight[flightLabels]--
+			m.observe(labels, elapsed)
+			m.mu.Unlock()
+			if p != nil {
+				// 记录为500后交给外层的Recovery处理
+				panic(p)
+			}
+		}()
+		c.Next()
+	}
+}
+
+func (m *MetricsRegistry) observe(labels string, seconds float64) {
+	r, ok := m.requests[labels]
+	if !ok {
+		r = &requestMetrics{buckets: make([]uint64, len(m.config.Buckets))}
+		m.requests[labels] = r
+	}
+	r.count++
+	r.sum += seconds
+	if i := sort.SearchFloat64s(m.config.Buckets, seconds); i < len(r.buckets) {
+		r.buckets[i]++
+	}
+}
+
+// 以Prometheus文本格式输出所有指标
+func (m *MetricsRegistry) ServeMetrics(c *Context) {
+	c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Data(http.StatusOK, m.Export())
+}
+
+func (m *MetricsRegistry) Export() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+	ns := m.config.Namespace
+	requestLabels := sortedKeys(m.requests)
+
+	writeMetricHeader(&buf, ns+"_http_requests_total", "counter", "Total number of HTTP requests.")
+	for _, labels := range requestLabels {
+		fmt.Fprintf(&buf, "%s_http_requests_total{%s} %d\n", ns, labels, m.requests[labels].count)
+	}
+
+	writeMetricHeader(&buf, ns+"_http_request_duration_seconds", "histogram", "HTTP request latency in seconds.")
+	for _, labels := range requestLabels {
+		r := m.requests[labels]
+		var cumulative uint64
+		for i, le := range m.config.Buckets {
+			cumulative += r.buckets[i]
+			fmt.Fprintf(&buf, "%s_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				ns, labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", ns, labels, r.count)
+		fmt.Fprintf(&buf, "%s_http_request_duration_seconds_sum{%s} %s\n", ns, labels, strconv.FormatFloat(r.sum, 'g', -1, 64))
+		fmt.Fprintf(&buf, "%s_http_request_duration_seconds_count{%s} %d\n", ns, labels, r.count)
+	}
+
+	writeMetricHeader(&buf, ns+"_http_requests_in_flight", "gauge", "Number of HTTP requests currently being served.")
+	inFlightLabels := make([]string, 0, len(m.inFlight))
+	for labels := range m.inFlight {
+		inFlightLabels = append(inFlightLabels, labels)
+	}
+	sort.Strings(inFlightLabels)
+	for _, labels := range inFlightLabels {
+		fmt.Fprintf(&buf, "%s_http_requests_in_flight{%s} %d\n", ns, labels, m.inFlight[labels])
+	}
+	return buf.Bytes()
+}
+
+func writeMetricHeader(buf *bytes.Buffer, name string, typ string, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sortedKeys(requests map[string]*requestMetrics) []string {
+	keys := make([]string, 0, len(requests))
+	for key := range requests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// 按name1, value1, name2, value2...的顺序生成标签，值需转义反斜杠、引号和换行
+func formatLabels(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i] + `="` + labelValueReplacer.Replace(pairs[i+1]) + `"`)
+	}
+	return b.String()
+}
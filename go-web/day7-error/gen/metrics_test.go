@@ -0,0 +1,51 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetricsRegistry(MetricsConfig{Buckets: []float64{0.1, 1}})
+	engine := New()
+	engine.Use(metrics.Handler())
+	engine.GET("/hello/:name", func(c *Context) {
+		c.String(http.StatusOK, "hello %s", c.Param("name"))
+	})
+	engine.GET("/metrics", metrics.ServeMetrics)
+
+	for _, path := range []string{"/hello/cucu", "/hello/love", "/missing"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	for _, method := range []string{"FOO", "get"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/missing", nil))
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		"# TYPE gen_http_requests_total counter",
+		`gen_http_requests_total{method="GET",route="/hello/:name",status="200"} 2`,
+		`gen_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`gen_http_requests_total{method="other",route="unmatched",status="404"} 2`,
+		`gen_http_request_duration_seconds_bucket{method="GET",route="/hello/:name",status="200",le="+Inf"} 2`,
+		`gen_http_request_duration_seconds_count{method="GET",route="/hello/:name",status="200"} 2`,
+		`gen_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics should contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/hello/cucu") || strings.Contains(body, "FOO") {
+		t.Fatal("raw path and method should not be used as label")
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	if got := formatLabels("route", `/a"b\c`+"\n"); got != `route="/a\"b\\c\n"` {
+		t.Fatalf("unexpected labels %s", got)
+	}
+}
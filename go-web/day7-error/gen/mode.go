@@ -0,0 +1,69 @@
+package gen
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+// 通过环境变量设置运行模式，例如GEN_MODE=release
+const EnvGenMode = "GEN_MODE"
+
+const (
+	// 输出路由注册等调试信息，模板文件变化时自动重新加载，Recovery日志附带请求内容
+	DebugMode = "debug"
+	// 不输出调试信息，没有额外的开销，用于生产环境
+	ReleaseMode = "release"
+	// 不输出日志，用于单元测试
+	TestMode = "test"
+)
+
+var genMode atomic.Value
+
+func init() {
+	SetMode(os.Getenv(EnvGenMode))
+}
+
+// 设置运行模式，为空时使用DebugMode。日志、模板重新加载等在使用时读取当前模式，
+// 因此New之后调用同样生效
+func SetMode(mode string) {
+	switch mode {
+	case "":
+		mode = DebugMode
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("gen: unknown mode " + mode + ", available modes: debug, release, test")
+	}
+	genMode.Store(mode)
+}
+
+func Mode() string {
+	return genMode.Load().(string)
+}
+
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
+
+// 调试信息，只在debug模式下输出
+func debugPrintf(format string, values ...interface{}) {
+	if IsDebugging() {
+		log.Printf("[GEN-debug] "+format, values...)
+	}
+}
+
+// 框架的日志，test模式下不输出
+func logPrintf(format string, values ...interface{}) {
+	if Mode() != TestMode {
+		log.Printf(format, values...)
+	}
+}
+
+// 启动服务时输出的提示
+func (engine *Engine) debugWarnings() {
+	debugPrintf("[WARNING] Running in debug mode. Switch to release mode in production: export %s=%s", EnvGenMode, ReleaseMode)
+	if len(engine.trustedProxies) == 0 {
+		debugPrintf("[WARNING] No trusted proxies set, ClientIP ignores X-Forwarded-For and X-Real-IP. " +
+			"Call SetTrustedProxies if the server runs behind a proxy.")
+	}
+}
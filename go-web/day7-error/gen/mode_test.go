@@ -0,0 +1,31 @@
+package gen
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	SetMode(TestMode)
+	os.Exit(m.Run())
+}
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(TestMode)
+
+	SetMode("")
+	if Mode() != DebugMode || !IsDebugging() {
+		t.Fatal("empty mode should be debug")
+	}
+	SetMode(ReleaseMode)
+	if Mode() != ReleaseMode || IsDebugging() {
+		t.Fatal("release mode should not be debugging")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unknown mode should panic")
+		}
+	}()
+	SetMode("production")
+}
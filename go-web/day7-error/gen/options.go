@@ -0,0 +1,90 @@
+package gen
+
+import (
+	"crypto/tls"
+	"os"
+	"time"
+)
+
+// Engine的可选配置，在New/Default时传入
+type Option func(*Engine)
+
+// http.Server相关配置，零值表示使用net/http的默认行为
+type serverOptions struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	tlsConfig         *tls.Config
+	selfSignedHosts   []string    // 非空时RunTLS未指定证书则生成自签名证书
+	unixSocketMode    os.FileMode // RunUnix创建的socket文件权限
+	h2c               bool        // 是否支持不加密的HTTP/2
+}
+
+// 读取整个请求（包括body）的超时时间
+func WithReadTimeout(d time.Duration) Option {
+	return func(engine *Engine) {
+		engine.options.readTimeout = d
+	}
+}
+
+// 读取请求header的超时时间
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(engine *Engine) {
+		engine.options.readHeaderTimeout = d
+	}
+}
+
+// 写响应的超时时间
+func WithWriteTimeout(d time.Duration) Option {
+	return func(engine *Engine) {
+		engine.options.writeTimeout = d
+	}
+}
+
+// keep-alive连接的空闲超时时间
+func WithIdleTimeout(d time.Duration) Option {
+	return func(engine *Engine) {
+		engine.options.idleTimeout = d
+	}
+}
+
+// 请求header的最大字节数
+func WithMaxHeaderBytes(n int) Option {
+	return func(engine *Engine) {
+		engine.options.maxHeaderBytes = n
+	}
+}
+
+// 自定义TLS配置，供RunTLS使用
+func WithTLSConfig(config *tls.Config) Option {
+	return func(engine *Engine) {
+		engine.options.tlsConfig = config
+	}
+}
+
+// RunTLS未指定证书文件时，在内存中为hosts生成自签名证书，仅用于开发环境
+func WithSelfSignedCert(hosts ...string) Option {
+	return func(engine *Engine) {
+		if len(hosts) == 0 {
+			hosts = []string{"localhost", "127.0.0.1"}
+		}
+		engine.options.selfSignedHosts = hosts
+	}
+}
+
+// RunUnix创建的socket文件权限，默认0660
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(engine *Engine) {
+		engine.options.unixSocketMode = mode
+	}
+}
+
+// 在不使用TLS的情况下支持HTTP/2（h2c），包括prior knowledge和Upgrade两种方式，
+// 适用于部署在h2c代理之后的服务
+func WithH2C() Option {
+	return func(engine *Engine) {
+		engine.options.h2c = true
+	}
+}
@@ -0,0 +1,159 @@
+package gen
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 限流速率：每Period最多Limit个请求，允许的突发量也为Limit
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// 单次限流判断的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 配额完全恢复所需的时间
+	RetryAfter time.Duration // 被限流时，至少需要等待的时间
+}
+
+// 限流数据的存储，可基于缓存集群实现以在多个实例间共享配额
+type RateLimitStore interface {
+	Take(key string, rate Rate) (RateLimitResult, error)
+}
+
+// 限流中间件的配置
+type RateLimitConfig struct {
+	Rate Rate
+	// 限流的维度，默认按客户端IP，返回空字符串表示不限流
+	KeyFunc func(c *Context) string
+	// 默认使用内存存储
+	Store RateLimitStore
+	// 被限流时的处理，默认返回429
+	Handler HandlerFunc
+}
+
+// 令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// 基于令牌桶的内存存储，空闲超过idleTimeout的桶会被清理
+type MemoryRateLimitStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	idleTimeout time.Duration
+	lastSweep   time.Time
+	now         func() time.Time
+}
+
+var _ RateLimitStore = &MemoryRateLimitStore{}
+
+func NewMemoryRateLimitStore(idleTimeout time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:     make(map[string]*bucket),
+		idleTimeout: idleTimeout,
+		lastSweep:   time.Now(),
+		now:         time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, rate Rate) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	limit := float64(rate.Limit)
+	perToken := rate.Period / time.Duration(rate.Limit) // 生成一个令牌所需的时间
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit, last: now}
+		s.buckets[key] = b
+	}
+	// 按经过的时间补充令牌
+	b.tokens = math.Min(limit, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	result := RateLimitResult{Limit: rate.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((limit - b.tokens) * float64(perToken))
+	return result, nil
+}
+
+// 清理空闲的桶，每idleTimeout最多执行一次
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if s.idleTimeout <= 0 || now.Sub(s.lastSweep) < s.idleTimeout {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= s.idleTimeout {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// 不足1秒的按1秒计算
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// 限流中间件，超出配额返回429，并设置Retry-After及RateLimit-*响应头
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	if config.Rate.Limit <= 0 || config.Rate.Period <= 0 {
+		panic("gen: RateLimit requires a positive Rate")
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(c *Context) string {
+			return c.ClientIP()
+		}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore(10 * config.Rate.Period)
+	}
+	if config.Handler == nil {
+		config.Handler = func(c *Context) {
+			c.Fail(http.StatusTooManyRequests, "Too Many Requests")
+		}
+	}
+
+	return func(c *Context) {
+		key := config.KeyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		result, err := config.Store.Take(key, config.Rate)
+		if err != nil { // 存储不可用时放行，避免影响正常请求
+			logPrintf("rate limit store error: %v", err)
+			c.Next()
+			return
+		}
+
+		c.SetHeader("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.SetHeader("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.SetHeader("RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			c.SetHeader("Retry-After", ceilSeconds(result.RetryAfter))
+			c.Abort()
+			config.Handler(c)
+			return
+		}
+		c.Next()
+	}
+}
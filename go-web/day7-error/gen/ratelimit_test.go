@@ -0,0 +1,82 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	engine := New()
+	engine.Use(RateLimit(RateLimitConfig{Rate: Rate{Limit: 2, Period: time.Minute}}))
+	engine.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d should be allowed, got %d", i, w.Code)
+		}
+	}
+	w := request("10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request should be limited, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected rate limit headers %v", w.Header())
+	}
+	if w := request("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Fatal("other clients should not be limited")
+	}
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	engine := New()
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.engine = engine
+	c.Req.RemoteAddr = "10.0.0.1:1234"
+	c.Req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	if c.ClientIP() != "10.0.0.1" {
+		t.Fatal("X-Forwarded-For should be ignored from untrusted proxies")
+	}
+
+	if err := engine.SetTrustedProxies([]string{"10.0.0.0/8", "2.2.2.2"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.ClientIP() != "1.1.1.1" {
+		t.Fatalf("client ip should be 1.1.1.1, got %s", c.ClientIP())
+	}
+}
+
+func TestMemoryRateLimitStoreRefillAndEvict(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore(time.Hour)
+	store.now = func() time.Time { return now }
+	rate := Rate{Limit: 1, Period: time.Second}
+
+	if r, _ := store.Take("a", rate); !r.Allowed {
+		t.Fatal("first take should be allowed")
+	}
+	if r, _ := store.Take("a", rate); r.Allowed {
+		t.Fatal("second take should be limited")
+	}
+	now = now.Add(time.Second)
+	if r, _ := store.Take("a", rate); !r.Allowed {
+		t.Fatal("token should be refilled after a period")
+	}
+
+	now = now.Add(2 * time.Hour)
+	store.Take("b", rate)
+	if _, ok := store.buckets["a"]; ok {
+		t.Fatal("idle bucket should be evicted")
+	}
+}
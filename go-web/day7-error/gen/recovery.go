@@ -1,13 +1,36 @@
 package gen
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
+// 发生panic时的处理函数，err为recover()得到的值
+type RecoveryHandlerFunc func(c *Context, err interface{})
+
+// Recovery中间件的配置
+type RecoveryConfig struct {
+	// 自定义panic处理，默认返回500
+	Handler RecoveryHandlerFunc
+	// 日志输出，默认与log包一致，test模式下不输出
+	Output io.Writer
+	// 是否在日志中附带请求内容
+	DumpRequest bool
+	// 需要脱敏的header，会与默认的Authorization、Cookie等合并
+	SensitiveHeaders []string
+}
+
+// 默认需要脱敏的header
+var defaultSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
 // print stack trace for debug
 func trace(message string) string {
 	var pcs [32]uintptr
@@ -15,21 +38,87 @@ func trace(message string) string {
 
 	var str strings.Builder
 	str.WriteString(message + "\nTraceback:")
-	for _, pc := range pcs[:n] {
-		fn := runtime.FuncForPC(pc)
-		file, line := fn.FileLine(pc)
-		str.WriteString(fmt.Sprintf("\n\t%s:%d", file, line))
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		str.WriteString(fmt.Sprintf("\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
 	}
 	return str.String()
 }
 
+// 判断是否是客户端断开连接导致的panic，这类错误无需返回500
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	return errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET)
+}
+
+// 导出请求内容，并将敏感header替换为*
+func dumpRequest(req *http.Request, sensitive []string) string {
+	raw, err := httputil.DumpRequest(req, false)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(bytes.TrimSpace(raw)), "\r\n")
+	for i, line := range lines {
+		idx := strings.Index(line, ":")
+		if i == 0 || idx < 0 {
+			continue
+		}
+		for _, key := range sensitive {
+			if strings.EqualFold(line[:idx], key) {
+				lines[i] = line[:idx] + ": *"
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func defaultRecoveryHandler(c *Context, err interface{}) {
+	c.Fail(http.StatusInternalServerError, "Internal Server Error")
+}
+
 func Recovery() HandlerFunc {
+	return RecoveryWithConfig(RecoveryConfig{})
+}
+
+func RecoveryWithConfig(config RecoveryConfig) HandlerFunc {
+	if config.Handler == nil {
+		config.Handler = defaultRecoveryHandler
+	}
+	// 未指定Output时使用框架的日志，输出时按当前模式判断，test模式下不输出
+	logf := logPrintf
+	if config.Output != nil {
+		logf = log.New(config.Output, "", log.LstdFlags).Printf
+	}
+	sensitive := append(append([]string{}, defaultSensitiveHeaders...), config.SensitiveHeaders...)
+
 	return func(c *Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				c.Fail(http.StatusInternalServerError, "Internal Server Error")
+				message := fmt.Sprintf("%s%s", err, c.logIDs())
+				if isBrokenPipe(err) { // 连接已断开，响应也发不出去了
+					logf("%s %s: %s\n\n", c.Method, c.Path, message)
+					c.Abort()
+					return
+				}
+
+				// debug模式下总是附带请求内容
+				if config.DumpRequest || IsDebugging() {
+					message += "\n" + dumpRequest(c.Req, sensitive)
+				}
+				logf("%s\n\n", trace(message))
+				if c.Writer.Written() { // header已发送，无法再修改响应
+					c.Abort()
+					return
+				}
+				config.Handler(c, err)
 			}
 		}()
 
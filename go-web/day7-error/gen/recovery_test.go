@@ -0,0 +1,121 @@
+package gen
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf}))
+	engine.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status should be 500, got %d", w.Code)
+	}
+	if !strings.Contains(buf.String(), "boom") || !strings.Contains(buf.String(), "gen.TestRecovery") {
+		t.Fatalf("trace should contain message and function name, got %s", buf.String())
+	}
+}
+
+func TestRecoveryCustomHandler(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(RecoveryWithConfig(RecoveryConfig{
+		Output: &buf,
+		Handler: func(c *Context, err interface{}) {
+			c.String(http.StatusServiceUnavailable, "%v", err)
+		},
+		DumpRequest: true,
+	}))
+	engine.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "boom" {
+		t.Fatalf("custom handler should be used, got %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(buf.String(), "secret") || !strings.Contains(buf.String(), "Authorization: *") {
+		t.Fatalf("Authorization should be redacted, got %s", buf.String())
+	}
+}
+
+func TestRecoveryAfterWritten(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf}))
+	engine.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("written response should not be changed, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRecoveryBrokenPipe(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(RecoveryWithConfig(RecoveryConfig{Output: &buf}))
+	engine.GET("/panic", func(c *Context) {
+		panic(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code == http.StatusInternalServerError {
+		t.Fatal("broken pipe should not respond 500")
+	}
+	if strings.Contains(buf.String(), "Traceback") {
+		t.Fatal("broken pipe should not print traceback")
+	}
+}
+
+func TestRecoveryModeAtLogTime(t *testing.T) {
+	var buf bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&buf)
+	defer func() {
+		SetMode(TestMode)
+		log.SetOutput(output)
+	}()
+
+	SetMode(DebugMode)
+	engine := New()
+	engine.Use(Recovery())
+	engine.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	// New之后切换到test模式，不再输出
+	SetMode(TestMode)
+	buf.Reset()
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("recovery should not log in test mode, got %q", buf.String())
+	}
+	SetMode(ReleaseMode)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	if !strings.Contains(buf.String(), "boom") {
+		t.Fatalf("recovery should log in release mode, got %q", buf.String())
+	}
+}
@@ -0,0 +1,356 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 渲染HTML的接口，可以替换为其他模板引擎。
+// funcs为按请求替换的模板函数（例如csrfField），没有时为nil
+type HTMLRender interface {
+	Render(w io.Writer, name string, data interface{}, funcs template.FuncMap) error
+}
+
+// 一组解析好的模板
+type htmlTemplate struct {
+	base *template.Template // 未执行过的模板，用于Clone
+	tmpl *template.Template // 直接渲染使用
+	root string             // 页面渲染时执行的模板，例如布局文件base.tmpl
+	// 按请求替换函数时使用的副本，渲染后恢复函数再放回，避免每次渲染都Clone整组模板
+	clones sync.Pool
+}
+
+func newHTMLTemplate(base *template.Template, root string) (*htmlTemplate, error) {
+	tmpl, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &htmlTemplate{base: base, tmpl: tmpl, root: root}, nil
+}
+
+// 使用替换了funcs的副本渲染，defaults为解析时的函数，用于渲染后恢复
+func (entry *htmlTemplate) executeWithFuncs(w io.Writer, root string, data interface{}, funcs template.FuncMap, defaults template.FuncMap) error {
+	clone, _ := entry.clones.Get().(*template.Template)
+	if clone == nil {
+		var err error
+		if clone, err = entry.base.Clone(); err != nil {
+			return err
+		}
+	}
+	err := clone.Funcs(funcs).ExecuteTemplate(w, root, data)
+
+	// 恢复为解析时的函数，避免下一个请求使用到本次请求的token等；无法恢复时丢弃副本
+	restore := make(template.FuncMap, len(funcs))
+	for name := range funcs {
+		fn, ok := defaults[name]
+		if !ok {
+			return err
+		}
+		restore[name] = fn
+	}
+	entry.clones.Put(clone.Funcs(restore))
+	return err
+}
+
+// 模板的来源，开启重新加载时按顺序重新解析
+type htmlSource struct {
+	page      string // 页面名，为空时为公共模板
+	root      string // 页面渲染时执行的模板
+	parse     func(*template.Template) (*template.Template, error)
+	signature func() string // 文件名、修改时间及大小，变化时重新解析
+}
+
+// 默认的HTMLRender，基于html/template。
+// ParseGlob、ParseFiles、ParseFS加载的模板按文件名或define的名称渲染，作为公共模板；
+// AddPage将公共模板、布局和页面组合为独立的一组，按页面名渲染，从而不同页面可以定义同名的block
+type HTMLTemplates struct {
+	funcs template.FuncMap
+
+	mu        sync.RWMutex
+	shared    *htmlTemplate
+	pages     map[string]*htmlTemplate
+	sources   []htmlSource
+	signature string
+	reload    bool
+	// 未调用SetReload时按渲染时的运行模式决定，debug模式下重新加载
+	modeReload bool
+}
+
+func NewHTMLTemplates(funcs template.FuncMap) *HTMLTemplates {
+	return &HTMLTemplates{funcs: funcs, pages: make(map[string]*htmlTemplate)}
+}
+
+// 开启后每次渲染前检查模板文件是否变化，变化后重新解析，用于开发环境
+func (t *HTMLTemplates) SetReload(reload bool) {
+	t.mu.Lock()
+	t.reload = reload
+	t.modeReload = false
+	t.mu.Unlock()
+}
+
+func (t *HTMLTemplates) ParseGlob(pattern string) error {
+	return t.add(htmlSource{
+		parse: func(tmpl *template.Template) (*template.Template, error) {
+			return tmpl.ParseGlob(pattern)
+		},
+		signature: func() string {
+			files, _ := filepath.Glob(pattern)
+			return filesSignature(os.Stat, files)
+		},
+	})
+}
+
+func (t *HTMLTemplates) ParseFiles(files ...string) error {
+	return t.add(htmlSource{
+		parse: func(tmpl *template.Template) (*template.Template, error) {
+			return tmpl.ParseFiles(files...)
+		},
+		signature: func() string {
+			return filesSignature(os.Stat, files)
+		},
+	})
+}
+
+// 从fs.FS（例如embed.FS）加载模板
+func (t *HTMLTemplates) ParseFS(fsys fs.FS, patterns ...string) error {
+	return t.add(htmlSource{
+		parse: func(tmpl *template.Template) (*template.Template, error) {
+			return tmpl.ParseFS(fsys, patterns...)
+		},
+		signature: func() string {
+			return fsSignature(fsys, patterns)
+		},
+	})
+}
+
+// 添加页面，files中第一个为布局文件，渲染时执行布局，布局中通过{{ template "content" . }}引用页面定义的block。
+// 页面中可以使用公共模板，因此需在ParseGlob等之后调用
+func (t *HTMLTemplates) AddPage(name string, files ...string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("gen: no files for html page %q", name)
+	}
+	return t.add(htmlSource{
+		page: name,
+		root: filepath.Base(files[0]),
+		parse: func(tmpl *template.Template) (*template.Template, error) {
+			return tmpl.ParseFiles(files...)
+		},
+		signature: func() string {
+			return filesSignature(os.Stat, files)
+		},
+	})
+}
+
+func (t *HTMLTemplates) AddPageFS(name string, fsys fs.FS, files ...string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("gen: no files for html page %q", name)
+	}
+	return t.add(htmlSource{
+		page: name,
+		root: path.Base(files[0]),
+		parse: func(tmpl *template.Template) (*template.Template, error) {
+			return tmpl.ParseFS(fsys, files...)
+		},
+		signature: func() string {
+			return fsSignature(fsys, files)
+		},
+	})
+}
+
+func (t *HTMLTemplates) add(source htmlSource) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.apply(source); err != nil {
+		return err
+	}
+	t.sources = append(t.sources, source)
+	t.signature = t.currentSignature()
+	return nil
+}
+
+// 基于已有的公共模板创建新的模板，未执行过的模板才能Clone
+func (t *HTMLTemplates) newBase() (*template.Template, error) {
+	if t.shared == nil {
+		return template.New("").Funcs(t.funcs), nil
+	}
+	return t.shared.base.Clone()
+}
+
+func (t *HTMLTemplates) apply(source htmlSource) error {
+	base, err := t.newBase()
+	if err != nil {
+		return err
+	}
+	if base, err = source.parse(base); err != nil {
+		return err
+	}
+	if source.page != "" && base.Lookup(source.root) == nil {
+		return fmt.Errorf("gen: layout %q not found for html page %q", source.root, source.page)
+	}
+	entry, err := newHTMLTemplate(base, source.root)
+	if err != nil {
+		return err
+	}
+	if source.page == "" {
+		t.shared = entry
+	} else {
+		t.pages[source.page] = entry
+	}
+	return nil
+}
+
+func (t *HTMLTemplates) currentSignature() string {
+	var b strings.Builder
+	for _, source := range t.sources {
+		b.WriteString(source.signature())
+	}
+	return b.String()
+}
+
+// 模板文件变化时按原来的顺序重新解析，失败时保留旧的模板，下次渲染时重试
+func (t *HTMLTemplates) reloadIfChanged() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	signature := t.currentSignature()
+	if signature == t.signature {
+		return nil
+	}
+	shared, pages := t.shared, t.pages
+	t.shared, t.pages = nil, make(map[string]*htmlTemplate)
+	for _, source := range t.sources {
+		if err := t.apply(source); err != nil {
+			t.shared, t.pages = shared, pages
+			return err
+		}
+	}
+	t.signature = signature
+	return nil
+}
+
+func filesSignature(stat func(name string) (os.FileInfo, error), files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		b.WriteString(file)
+		if info, err := stat(file); err == nil {
+			b.WriteString("|" + strconv.FormatInt(info.ModTime().UnixNano(), 10) + "|" + strconv.FormatInt(info.Size(), 10))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func fsSignature(fsys fs.FS, patterns []string) string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, _ := fs.Glob(fsys, pattern)
+		files = append(files, matches...)
+	}
+	return filesSignature(func(name string) (os.FileInfo, error) { return fs.Stat(fsys, name) }, files)
+}
+
+// 优先按页面名渲染，否则渲染公共模板中的name
+func (t *HTMLTemplates) Render(w io.Writer, name string, data interface{}, funcs template.FuncMap) error {
+	t.mu.RLock()
+	reload := t.reload || (t.modeReload && IsDebugging())
+	t.mu.RUnlock()
+	if reload {
+		if err := t.reloadIfChanged(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.RLock()
+	entry, root := t.pages[name], ""
+	if entry != nil {
+		root = entry.root
+	} else if entry = t.shared; entry != nil {
+		root = name
+	}
+	t.mu.RUnlock()
+	if entry == nil || entry.base.Lookup(root) == nil {
+		return fmt.Errorf("gen: html template %q is undefined", name)
+	}
+
+	if len(funcs) > 0 {
+		return entry.executeWithFuncs(w, root, data, funcs, t.funcs)
+	}
+	return entry.tmpl.ExecuteTemplate(w, root, data)
+}
+
+// 渲染时使用的缓冲区，模板出错时不会输出半个页面
+var htmlBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// 设置group下的路由使用的HTMLRender，未设置时使用上级group的
+func (group *RouterGroup) SetHTMLRender(render HTMLRender) {
+	group.htmlRender = render
+}
+
+// 加载模板，group下的路由使用这组模板，SetFuncMap需在此之前调用
+func (group *RouterGroup) LoadHTMLGlob(pattern string) {
+	group.loadHTML(func(t *HTMLTemplates) error { return t.ParseGlob(pattern) })
+}
+
+func (group *RouterGroup) LoadHTMLFiles(files ...string) {
+	group.loadHTML(func(t *HTMLTemplates) error { return t.ParseFiles(files...) })
+}
+
+func (group *RouterGroup) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	group.loadHTML(func(t *HTMLTemplates) error { return t.ParseFS(fsys, patterns...) })
+}
+
+// 模板有误时panic，与template.Must一致
+func (group *RouterGroup) loadHTML(parse func(t *HTMLTemplates) error) {
+	t := group.newHTMLTemplates()
+	if err := parse(t); err != nil {
+		panic(err)
+	}
+	group.htmlRender = t
+}
+
+// 获取group自己的HTMLTemplates，不存在时创建，用于添加布局和页面：
+//
+//	t := engine.HTMLTemplates()
+//	t.ParseGlob("templates/partials/*")
+//	t.AddPage("index", "templates/layouts/base.tmpl", "templates/index.tmpl")
+func (group *RouterGroup) HTMLTemplates() *HTMLTemplates {
+	if t, ok := group.htmlRender.(*HTMLTemplates); ok {
+		return t
+	}
+	t := group.newHTMLTemplates()
+	group.htmlRender = t
+	return t
+}
+
+// 使用engine的模板函数及重新加载的设置创建HTMLTemplates
+func (group *RouterGroup) newHTMLTemplates() *HTMLTemplates {
+	t := NewHTMLTemplates(group.engine.templateFuncs())
+	if reload := group.engine.htmlReload; reload != nil {
+		t.reload = *reload
+	} else {
+		t.modeReload = true
+	}
+	return t
+}
+
+// 开启后group中通过LoadHTMLGlob等加载的模板在文件变化时自动重新解析，
+// 无需重启服务，用于开发环境；关闭时渲染没有额外开销。
+// 未调用时按渲染时的运行模式决定，debug模式下开启，New之后调用SetMode同样生效
+func (engine *Engine) SetHTMLReload(reload bool) {
+	engine.htmlReload = &reload
+	for _, group := range engine.groups {
+		if t, ok := group.htmlRender.(*HTMLTemplates); ok {
+			t.SetReload(reload)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package gen
+
+import (
+	"html"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHTMLTemplates(t *testing.T) {
+	files := fstest.MapFS{
+		"layouts/base.tmpl": {Data: []byte(`<html>{{ template "nav" . }}{{ template "content" . }}</html>`)},
+		"partials/nav.tmpl": {Data: []byte(`{{ define "nav" }}<nav>{{ .user }}</nav>{{ end }}`)},
+		"pages/index.tmpl":  {Data: []byte(`{{ define "content" }}<p>index</p>{{ end }}`)},
+		"pages/about.tmpl":  {Data: []byte(`{{ define "content" }}<p>about {{ .missing.field }}</p>{{ end }}`)},
+		"admin/panel.tmpl":  {Data: []byte(`<h1>admin {{ .user }}</h1>`)},
+	}
+
+	engine := New()
+	site := engine.HTMLTemplates()
+	if err := site.ParseFS(files, "partials/*.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []string{"index", "about"} {
+		if err := site.AddPageFS(page, files, "layouts/base.tmpl", "pages/"+page+".tmpl"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := engine.Group("/admin")
+	admin.LoadHTMLFS(files, "admin/*.tmpl")
+	noTemplates := New()
+	noTemplates.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "index", nil)
+	})
+
+	engine.GET("/:page", func(c *Context) {
+		c.HTML(http.StatusOK, c.Param("page"), H{"user": "cucu", "missing": 1})
+	})
+	admin.GET("/panel", func(c *Context) {
+		c.HTML(http.StatusOK, "panel.tmpl", H{"user": "cucu"})
+	})
+
+	get := func(engine *Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get(engine, "/index"); w.Code != http.StatusOK || w.Body.String() != "<html><nav>cucu</nav><p>index</p></html>" {
+		t.Fatalf("unexpected page %d %q", w.Code, w.Body.String())
+	}
+	if w := get(engine, "/admin/panel"); w.Code != http.StatusOK || w.Body.String() != "<h1>admin cucu</h1>" {
+		t.Fatalf("group should use its own templates, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get(engine, "/about"); w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<html>") {
+		t.Fatalf("render error should be a clean 500, got %d %q", w.Code, w.Body.String())
+	}
+	if w := get(engine, "/missing"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("undefined template should return 500, got %d", w.Code)
+	}
+	if w := get(noTemplates, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("HTML without templates should return 500, got %d", w.Code)
+	}
+}
+
+func TestHTMLTemplateFuncs(t *testing.T) {
+	engine := New()
+	engine.Use(Secure(SecureConfig{ContentSecurityPolicy: "script-src 'nonce-{nonce}'"}))
+	engine.LoadHTMLFS(fstest.MapFS{"nonce.tmpl": {Data: []byte(`<script nonce="{{ cspNonce }}"></script>`)}}, "*.tmpl")
+	engine.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "nonce.tmpl", nil)
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	nonce := strings.TrimPrefix(strings.TrimSuffix(w.Header().Get("Content-Security-Policy"), "'"), "script-src 'nonce-")
+	// nonce中的+等字符在属性中会被转义
+	if nonce == "" || html.UnescapeString(w.Body.String()) != `<script nonce="`+nonce+`"></script>` {
+		t.Fatalf("per-request template funcs should be applied, got %q", w.Body.String())
+	}
+}
+
+func TestHTMLTemplateFuncsReuse(t *testing.T) {
+	templates := NewHTMLTemplates(template.FuncMap{"token": func() string { return "none" }})
+	if err := templates.ParseFS(fstest.MapFS{"token.tmpl": {Data: []byte(`{{ token }}`)}}, "*.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+	render := func(funcs template.FuncMap) string {
+		var b strings.Builder
+		if err := templates.Render(&b, "token.tmpl", nil, funcs); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+			if got := render(template.FuncMap{"token": func() string { return token }}); got != token {
+				t.Errorf("render should use its own funcs, got %q want %q", got, token)
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	// 复用的副本恢复为默认函数，不会泄露其他请求的值
+	if got := render(template.FuncMap{"other": func() string { return "" }}); got != "none" {
+		t.Fatalf("reused template should restore default funcs, got %q", got)
+	}
+	if got := render(nil); got != "none" {
+		t.Fatalf("render without funcs should use defaults, got %q", got)
+	}
+}
+
+func TestHTMLReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "page.tmpl")
+	write := func(content string, mtime time.Time) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(file, mtime, mtime)
+	}
+	write(`v1 {{ upper "a" }}`, time.Now().Add(-time.Hour))
+
+	engine := New()
+	engine.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	engine.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "page.tmpl", nil)
+	})
+	render := func() string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Body.String()
+	}
+
+	if body := render(); body != "v1 A" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	write(`v2 {{ upper "b" }}`, time.Now())
+	if body := render(); body != "v1 A" {
+		t.Fatalf("templates should not reload by default, got %q", body)
+	}
+	engine.SetHTMLReload(true)
+	if body := render(); body != "v2 B" {
+		t.Fatalf("templates should reload after change, got %q", body)
+	}
+	write(`v3 {{ upper`, time.Now().Add(time.Hour))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("broken template should return 500, got %d", w.Code)
+	}
+}
+
+func TestHTMLReloadFollowsMode(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "page.tmpl")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1")
+
+	SetMode(DebugMode)
+	defer SetMode(TestMode)
+	engine := New()
+	engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	engine.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "page.tmpl", nil)
+	})
+	render := func() string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Body.String()
+	}
+
+	// New之后切换到release模式，不再重新加载
+	SetMode(ReleaseMode)
+	write("v2-release")
+	if body := render(); body != "v1" {
+		t.Fatalf("templates should not reload in release mode, got %q", body)
+	}
+	SetMode(DebugMode)
+	if body := render(); body != "v2-release" {
+		t.Fatalf("templates should reload in debug mode, got %q", body)
+	}
+	// 显式设置后不再跟随运行模式
+	engine.SetHTMLReload(false)
+	write("v3-explicit")
+	if body := render(); body != "v2-release" {
+		t.Fatalf("SetHTMLReload(false) should disable reload, got %q", body)
+	}
+}
@@ -0,0 +1,144 @@
+package gen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+
+	// 保存在Context中的key
+	requestIDKey    = "gen.requestID"
+	traceContextKey = "gen.traceContext"
+)
+
+// W3C Trace Context，见https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	TraceID  string // 32位十六进制，整条链路共用
+	SpanID   string // 16位十六进制，当前服务处理本次请求的span
+	ParentID string // 上游服务的span，新建链路时为空
+	Flags    string // trace-flags，例如01表示采样
+	State    string // tracestate，原样透传
+}
+
+// 生成traceparent，调用下游服务时使用
+func (t TraceContext) TraceParent() string {
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + t.Flags
+}
+
+// 将trace信息写入请求头，传递给下游服务
+func (t TraceContext) Inject(header http.Header) {
+	if t.TraceID == "" {
+		return
+	}
+	header.Set(HeaderTraceParent, t.TraceParent())
+	if t.State != "" {
+		header.Set(HeaderTraceState, t.State)
+	}
+}
+
+// 生成n字节的随机十六进制字符串
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// 判断是否为指定长度、非全0的小写十六进制
+func isValidHexID(s string, length int) bool {
+	if len(s) != length || strings.Trim(s, "0") == "" {
+		return false
+	}
+	for _, ch := range s {
+		if !(ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// 解析traceparent，格式为version-traceid-parentid-flags
+func ParseTraceParent(value string) (TraceContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || (len(value) > 55 && value[55] != '-') {
+		return TraceContext{}, false
+	}
+	parts := strings.SplitN(value[:55], "-", 4)
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return TraceContext{}, false
+	}
+	// 00版本不允许有额外字段
+	if parts[0] == "00" && len(value) != 55 {
+		return TraceContext{}, false
+	}
+	if _, err := hex.DecodeString(parts[0] + parts[3]); err != nil {
+		return TraceContext{}, false
+	}
+	if !isValidHexID(parts[1], 32) || !isValidHexID(parts[2], 16) {
+		return TraceContext{}, false
+	}
+	return TraceContext{TraceID: parts[1], ParentID: parts[2], Flags: parts[3]}, true
+}
+
+// 判断外部传入的request id是否可用，避免日志注入
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, ch := range id {
+		if ch < 0x21 || ch > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// 读取或生成X-Request-ID，解析并传递traceparent/tracestate，并在响应头中返回
+func RequestID() HandlerFunc {
+	return func(c *Context) {
+		id := c.Req.Header.Get(HeaderRequestID)
+		if !isValidRequestID(id) {
+			id = randomHex(16)
+		}
+
+		trace, ok := ParseTraceParent(c.Req.Header.Get(HeaderTraceParent))
+		if ok {
+			trace.State = c.Req.Header.Get(HeaderTraceState)
+		} else { // 没有上游，作为链路的起点
+			trace = TraceContext{TraceID: randomHex(16), Flags: "01"}
+		}
+		trace.SpanID = randomHex(8)
+
+		c.Set(requestIDKey, id)
+		c.Set(traceContextKey, trace)
+		c.SetHeader(HeaderRequestID, id)
+		trace.Inject(c.Writer.Header())
+		c.Next()
+	}
+}
+
+// 获取本次请求的request id，未使用RequestID中间件时为空
+func (c *Context) RequestID() string {
+	return c.GetString(requestIDKey)
+}
+
+// 获取本次请求的trace信息，未使用RequestID中间件时为零值
+func (c *Context) TraceContext() TraceContext {
+	value, _ := c.Get(traceContextKey)
+	trace, _ := value.(TraceContext)
+	return trace
+}
+
+// 日志中附带的request id及trace id
+func (c *Context) logIDs() string {
+	id := c.RequestID()
+	if id == "" {
+		return ""
+	}
+	return " request_id=" + id + " trace_id=" + c.TraceContext().TraceID
+}
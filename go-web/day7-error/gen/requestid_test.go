@@ -0,0 +1,101 @@
+package gen
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	valid := "00-" + traceID + "-" + parentID + "-01"
+	trace, ok := ParseTraceParent(valid)
+	if !ok || trace.TraceID != traceID || trace.ParentID != parentID || trace.Flags != "01" {
+		t.Fatalf("ParseTraceParent(%q) = %+v, %v", valid, trace, ok)
+	}
+	// 未来版本允许有额外字段
+	if _, ok := ParseTraceParent("01-" + traceID + "-" + parentID + "-01-extra"); !ok {
+		t.Fatal("future version with extra fields should be accepted")
+	}
+
+	invalid := []string{
+		"",
+		"ff-" + traceID + "-" + parentID + "-01",
+		"00-" + strings.Repeat("0", 32) + "-" + parentID + "-01",
+		"00-" + traceID + "-" + strings.Repeat("0", 16) + "-01",
+		"00-" + strings.ToUpper(traceID) + "-" + parentID + "-01",
+		"00-" + traceID + "-" + parentID + "-01-extra",
+		"00-" + traceID + "-" + parentID + "-zz",
+		"00-" + traceID[:31] + "-" + parentID + "-01",
+	}
+	for _, value := range invalid {
+		if _, ok := ParseTraceParent(value); ok {
+			t.Fatalf("ParseTraceParent(%q) should fail", value)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Use(RequestID(), Logger(), RecoveryWithConfig(RecoveryConfig{Output: &buf}))
+	engine.GET("/ping", func(c *Context) {
+		c.String(http.StatusOK, c.RequestID()+" "+c.TraceContext().TraceID)
+	})
+	engine.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	req.Header.Set(HeaderTraceParent, traceParent)
+	req.Header.Set(HeaderTraceState, "vendor=1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Body.String() != "req-1 4bf92f3577b34da6a3ce929d0e0e4736" || w.Header().Get(HeaderRequestID) != "req-1" {
+		t.Fatalf("request id and trace id should be kept, got %q %v", w.Body.String(), w.Header())
+	}
+	trace, ok := ParseTraceParent(w.Header().Get(HeaderTraceParent))
+	if !ok || trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.ParentID == "00f067aa0ba902b7" || w.Header().Get(HeaderTraceState) != "vendor=1" {
+		t.Fatalf("traceparent should carry a new span of the same trace, got %v", w.Header())
+	}
+
+	// 非法的request id重新生成
+	req = httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set(HeaderRequestID, "bad id\n")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if id := w.Header().Get(HeaderRequestID); !isValidHexID(id, 32) {
+		t.Fatalf("invalid request id should be regenerated, got %q", id)
+	}
+
+	// Logger在测试模式下不输出，临时切换到release模式
+	var logBuf bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&logBuf)
+	SetMode(ReleaseMode)
+	defer func() {
+		SetMode(TestMode)
+		log.SetOutput(output)
+	}()
+
+	req = httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set(HeaderRequestID, "req-2")
+	req.Header.Set(HeaderTraceParent, traceParent)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	ids := "request_id=req-2 trace_id=4bf92f3577b34da6a3ce929d0e0e4736"
+	if w.Header().Get(HeaderRequestID) != "req-2" || w.Header().Get(HeaderTraceParent) == "" {
+		t.Fatalf("ids should be echoed in the response, got %v", w.Header())
+	}
+	if !strings.Contains(buf.String(), ids) {
+		t.Fatalf("recovery output should contain ids, got %q", buf.String())
+	}
+	if !strings.Contains(logBuf.String(), ids) {
+		t.Fatalf("logger output should contain ids, got %q", logBuf.String())
+	}
+}
@@ -0,0 +1,311 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"gencache"
+	"gencache/singleflight"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 响应缓存的配置
+type ResponseCacheConfig struct {
+	// gencache中group的名称，默认gen-response-cache，多个缓存需使用不同的名称
+	Name string
+	// 缓存的最大字节数，默认64MB
+	CacheBytes int64
+	// 默认的缓存时间，handler返回的Cache-Control max-age优先
+	TTL time.Duration
+	// 参与计算缓存key的请求头，例如Accept-Language
+	VaryHeaders []string
+}
+
+// 缓存的完整响应
+type cachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Expires time.Time
+}
+
+var errResponseNotCached = errors.New("gen: response not cached")
+
+// 基于gencache的HTTP响应缓存，只缓存GET请求的200响应
+type ResponseCache struct {
+	config ResponseCacheConfig
+	group  *gencache.Group
+	loader *singleflight.Group
+
+	mu           sync.Mutex
+	generation   uint64            // Purge后递增，使所有旧缓存失效
+	pathVersions map[string]uint64 // Invalidate后递增，使某个路径的缓存失效
+	keyVersions  map[string]uint64 // 缓存过期后递增，使过期的缓存不再命中
+
+	pending sync.Map // 待写入gencache的响应，key -> []byte
+}
+
+func NewResponseCache(config ResponseCacheConfig) *ResponseCache {
+	if config.Name == "" {
+		config.Name = "gen-response-cache"
+	}
+	if config.CacheBytes <= 0 {
+		config.CacheBytes = 64 << 20
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	// gencache按名称全局注册，同名的group会替换掉之前的
+	if gencache.GetGroup(config.Name) != nil {
+		panic(fmt.Sprintf("gen: response cache %q already exists", config.Name))
+	}
+	rc := &ResponseCache{
+		config:       config,
+		loader:       &singleflight.Group{},
+		pathVersions: make(map[string]uint64),
+		keyVersions:  make(map[string]uint64),
+	}
+	// gencache只能通过getter写入，未命中时getter返回错误，不会缓存
+	rc.group = gencache.NewGroup(config.Name, config.CacheBytes, gencache.GetterFunc(func(key string) ([]byte, error) {
+		if value, ok := rc.pending.Load(key); ok {
+			return value.([]byte), nil
+		}
+		return nil, errResponseNotCached
+	}))
+	return rc
+}
+
+// 底层的gencache group，缓存只保存在本节点
+func (rc *ResponseCache) Group() *gencache.Group {
+	return rc.group
+}
+
+// 使某个路径下所有查询参数的缓存失效
+func (rc *ResponseCache) Invalidate(path string) {
+	rc.mu.Lock()
+	rc.pathVersions[path]++
+	rc.mu.Unlock()
+}
+
+// 使所有缓存失效
+func (rc *ResponseCache) Purge() {
+	rc.mu.Lock()
+	rc.generation++
+	rc.pathVersions = make(map[string]uint64)
+	rc.keyVersions = make(map[string]uint64)
+	rc.mu.Unlock()
+}
+
+// 使用默认的缓存时间
+func (rc *ResponseCache) Handler() HandlerFunc {
+	return rc.Cache(rc.config.TTL)
+}
+
+// 指定缓存时间，可用于单个路由
+func (rc *ResponseCache) Cache(ttl time.Duration) HandlerFunc {
+	return func(c *Context) {
+		if c.Method != http.MethodGet || c.Req.Header.Get("Authorization") != "" {
+			c.Next()
+			return
+		}
+
+		base := rc.baseKey(c)
+		key := rc.versionedKey(c.Path, base)
+		if !strings.Contains(c.Req.Header.Get("Cache-Control"), "no-cache") {
+			// Lookup只查询本地缓存，不会像Get一样输出命中日志
+			if view, ok := rc.group.Lookup(key); ok {
+				if res, err := decodeCachedResponse(view.ByteSlice()); err == nil {
+					if time.Now().Before(res.Expires) {
+						rc.write(c, res, "HIT")
+						return
+					}
+					rc.expire(base)
+					key = rc.versionedKey(c.Path, base)
+				}
+			}
+		}
+
+		// 相同key的请求合并，只执行一次handler
+		leader := false
+		var panicValue interface{}
+		value, err := rc.loader.Do(key, func() (interface{}, error) {
+			leader = true
+			defer func() {
+				if p := recover(); p != nil {
+					panicValue = p
+				}
+			}()
+			return rc.load(c, key, ttl), nil
+		})
+		if panicValue != nil {
+			panic(panicValue)
+		}
+		if leader {
+			rc.write(c, value.(*cachedResponse), "MISS")
+			return
+		}
+		res, _ := value.(*cachedResponse)
+		if err != nil || res == nil || !rc.cacheable(res) {
+			// 响应不可共享，自己执行handler
+			c.Next()
+			return
+		}
+		rc.write(c, res, "MISS")
+	}
+}
+
+// 执行handler并将可缓存的响应写入gencache
+func (rc *ResponseCache) load(c *Context, key string, ttl time.Duration) *cachedResponse {
+	w := &captureWriter{ResponseWriter: c.Writer, header: make(http.Header), status: http.StatusOK}
+	c.Writer = w
+	func() {
+		defer func() { c.Writer = w.ResponseWriter }()
+		c.Next()
+	}()
+
+	res := &cachedResponse{Status: w.status, Header: w.header, Body: w.buf.Bytes()}
+	if !rc.cacheable(res) {
+		return res
+	}
+	res.Expires = time.Now().Add(responseTTL(res.Header, ttl))
+	data, err := encodeCachedResponse(res)
+	if err != nil {
+		return res
+	}
+	rc.pending.Store(key, data)
+	if _, ok := rc.group.Lookup(key); !ok {
+		rc.group.Get(key)
+	}
+	rc.pending.Delete(key)
+	return res
+}
+
+// 只缓存200且未禁止缓存、不设置cookie的响应
+func (rc *ResponseCache) cacheable(res *cachedResponse) bool {
+	if res.Status != http.StatusOK || res.Header.Get("Set-Cookie") != "" || res.Header.Get("Vary") == "*" {
+		return false
+	}
+	cc := res.Header.Get("Cache-Control")
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if strings.Contains(cc, directive) {
+			return false
+		}
+	}
+	return true
+}
+
+// handler设置了max-age/s-maxage时优先使用
+func responseTTL(header http.Header, ttl time.Duration) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		for _, prefix := range []string{"s-maxage=", "max-age="} {
+			if strings.HasPrefix(directive, prefix) {
+				if seconds, err := strconv.Atoi(directive[len(prefix):]); err == nil {
+					return time.Duration(seconds) * time.Second
+				}
+			}
+		}
+	}
+	return ttl
+}
+
+// 由方法、路径、排序后的查询参数及指定的请求头组成
+func (rc *ResponseCache) baseKey(c *Context) string {
+	query := c.Req.URL.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	var key strings.Builder
+	key.WriteString(c.Method + " " + c.Path + "?" + query.Encode())
+	for _, name := range rc.config.VaryHeaders {
+		key.WriteString("|" + name + "=" + url.QueryEscape(c.Req.Header.Get(name)))
+	}
+	return key.String()
+}
+
+// 在baseKey的基础上加入各级版本号，版本变化后旧缓存不再命中，由LRU自然淘汰
+func (rc *ResponseCache) versionedKey(path string, base string) string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return fmt.Sprintf("%d/%d/%d/%s", rc.generation, rc.pathVersions[path], rc.keyVersions[base], base)
+}
+
+func (rc *ResponseCache) expire(base string) {
+	rc.mu.Lock()
+	rc.keyVersions[base]++
+	rc.mu.Unlock()
+}
+
+// 输出缓存的响应，并终止后续handler的执行
+func (rc *ResponseCache) write(c *Context, res *cachedResponse, state string) {
+	c.Abort()
+	header := c.Writer.Header()
+	for k, v := range res.Header {
+		header[k] = v
+	}
+	header.Set("X-Cache", state)
+	c.Data(res.Status, res.Body)
+}
+
+func encodeCachedResponse(res *cachedResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(res)
+	return buf.Bytes(), err
+}
+
+func decodeCachedResponse(data []byte) (*cachedResponse, error) {
+	res := &cachedResponse{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(res)
+	return res, err
+}
+
+// 捕获handler的输出，header与外层中间件设置的分开保存
+type captureWriter struct {
+	ResponseWriter
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (w *captureWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.buf.Write(data)
+}
+
+// 需要缓存完整响应，忽略Flush
+func (w *captureWriter) Flush() {}
+
+func (w *captureWriter) Status() int {
+	return w.status
+}
+
+func (w *captureWriter) Size() int {
+	return w.buf.Len()
+}
+
+func (w *captureWriter) Written() bool {
+	return w.wroteHeader
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,113 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := NewResponseCache(ResponseCacheConfig{Name: uniqueCacheName(), TTL: time.Minute})
+	engine := New()
+	var calls int32
+	engine.GET("/items", cache.Handler(), func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusOK, H{"page": c.Query("page")})
+	})
+	engine.GET("/private", cache.Handler(), func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		c.SetHeader("Cache-Control", "private")
+		c.String(http.StatusOK, "private")
+	})
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	if w := get("/items?page=1&size=10"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("first request should miss")
+	}
+	// 命中时不应输出gencache的日志
+	var logBuf bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&logBuf)
+	w := get("/items?size=10&page=1")
+	log.SetOutput(output)
+	if logBuf.Len() != 0 {
+		t.Fatalf("cache hit should not log, got %q", logBuf.String())
+	}
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "{\"page\":\"1\"}\n" {
+		t.Fatalf("same normalized query should hit, got %s %s", w.Header().Get("X-Cache"), w.Body.String())
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatal("handler should be called once")
+	}
+
+	cache.Invalidate("/items")
+	if w := get("/items?page=1&size=10"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("invalidated path should miss")
+	}
+
+	get("/private")
+	get("/private")
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Fatalf("private responses should not be cached, calls=%d", calls)
+	}
+}
+
+func TestResponseCacheCoalescing(t *testing.T) {
+	cache := NewResponseCache(ResponseCacheConfig{Name: uniqueCacheName()})
+	engine := New()
+	var calls int32
+	release := make(chan struct{})
+	engine.GET("/slow", cache.Handler(), func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		c.String(http.StatusOK, "slow")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+			if w.Body.String() != "slow" {
+				t.Errorf("unexpected body %q", w.Body.String())
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("concurrent requests should be coalesced, calls=%d", calls)
+	}
+}
+
+var cacheNameSeq int32
+
+// gencache的group全局注册，-count多次执行时也需使用不同的名称
+func uniqueCacheName() string {
+	return fmt.Sprintf("test-response-cache-%d", atomic.AddInt32(&cacheNameSeq, 1))
+}
+
+func TestResponseCacheDuplicateName(t *testing.T) {
+	name := uniqueCacheName()
+	NewResponseCache(ResponseCacheConfig{Name: name})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate cache name should panic")
+		}
+	}()
+	NewResponseCache(ResponseCacheConfig{Name: name})
+}
@@ -7,13 +7,13 @@ import (
 
 type router struct {
 	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	handlers map[string][]HandlerFunc
 }
 
 func newRouter() *router {
 	return &router{
 		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		handlers: make(map[string][]HandlerFunc),
 	}
 }
 
@@ -34,7 +34,7 @@ func parsePattern(pattern string) []string {
 }
 
 // 添加路由
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
+func (r *router) addRoute(method string, pattern string, handlers []HandlerFunc) {
 	_, ok := r.roots[method]
 	if !ok {
 		r.roots[method] = &node{}
@@ -42,7 +42,7 @@ func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
 
 	parts := parsePattern(pattern)
 	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[routeKey(method, pattern)] = handler
+	r.handlers[routeKey(method, pattern)] = handlers
 }
 
 // 获取map中的路由key
@@ -92,7 +92,8 @@ func (r *router) handle(c *Context) {
 	n, params := r.getRoute(c.Method, c.Path) // 解析路由
 	if n != nil {                             // 路由存在，则执行对应的处理逻辑
 		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[routeKey(c.Method, n.pattern)])
+		c.fullPath = n.pattern
+		c.handlers = append(c.handlers, r.handlers[routeKey(c.Method, n.pattern)]...)
 	} else { // 路由不存在，则404
 		c.handlers = append(c.handlers, func(c *Context) {
 			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
@@ -0,0 +1,144 @@
+package gen
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 保存在Context中的key
+const cspNonceKey = "gen.cspNonce"
+
+// 安全响应头中间件的配置
+type SecureConfig struct {
+	// HSTS的有效期，为0时不设置，只在HTTPS请求中返回
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// Content-Security-Policy，其中的{nonce}会被替换为本次请求的随机值，
+	// 例如"script-src 'self' 'nonce-{nonce}'"，模板中通过{{ cspNonce }}获取
+	ContentSecurityPolicy string
+	// 默认DENY
+	FrameOptions string
+	// 默认nosniff
+	ContentTypeOptions string
+	// 默认strict-origin-when-cross-origin
+	ReferrerPolicy string
+	// 为空时不设置
+	PermissionsPolicy string
+	// 将HTTP请求重定向到HTTPS
+	SSLRedirect bool
+	// 重定向使用的host，为空时使用请求的host
+	SSLHost string
+	// 在代理之后时用于判断原始请求是否为HTTPS，例如{"X-Forwarded-Proto": "https"}
+	SSLProxyHeaders map[string]string
+	// 允许的Host，为空时不限制
+	AllowedHosts []string
+}
+
+// 获取本次请求的CSP nonce，未使用Secure中间件时为空
+func (c *Context) CSPNonce() string {
+	return c.GetString(cspNonceKey)
+}
+
+// 判断请求是否通过HTTPS
+func (config *SecureConfig) isHTTPS(c *Context) bool {
+	if c.Req.TLS != nil {
+		return true
+	}
+	for key, value := range config.SSLProxyHeaders {
+		if strings.EqualFold(c.Req.Header.Get(key), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (config *SecureConfig) allowHost(host string) bool {
+	if len(config.AllowedHosts) == 0 {
+		return true
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, allowed := range config.AllowedHosts {
+		if strings.EqualFold(allowed, host) || strings.EqualFold(allowed, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// 设置HSTS、CSP、X-Frame-Options等安全相关的响应头，可选强制HTTPS和限制Host
+func Secure(config SecureConfig) HandlerFunc {
+	if config.FrameOptions == "" {
+		config.FrameOptions = "DENY"
+	}
+	if config.ContentTypeOptions == "" {
+		config.ContentTypeOptions = "nosniff"
+	}
+	if config.ReferrerPolicy == "" {
+		config.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(config.HSTSMaxAge/time.Second))
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(c *Context) {
+		if !config.allowHost(c.Req.Host) {
+			c.Fail(http.StatusBadRequest, "bad host")
+			return
+		}
+
+		https := config.isHTTPS(c)
+		if config.SSLRedirect && !https {
+			host := config.SSLHost
+			if host == "" {
+				host = c.Req.Host
+			}
+			code := http.StatusMovedPermanently
+			if c.Method != http.MethodGet && c.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect // 保留请求方法和body
+			}
+			c.SetHeader("Location", "https://"+host+c.Req.URL.RequestURI())
+			c.AbortWithStatus(code)
+			return
+		}
+
+		header := c.Writer.Header()
+		if hsts != "" && https {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if config.ContentSecurityPolicy != "" {
+			csp := config.ContentSecurityPolicy
+			if strings.Contains(csp, "{nonce}") {
+				b := make([]byte, 16)
+				rand.Read(b)
+				nonce := base64.StdEncoding.EncodeToString(b)
+				c.Set(cspNonceKey, nonce)
+				c.setTemplateFunc("cspNonce", c.CSPNonce)
+				csp = strings.Replace(csp, "{nonce}", nonce, -1)
+			}
+			header.Set("Content-Security-Policy", csp)
+		}
+		header.Set("X-Frame-Options", config.FrameOptions)
+		header.Set("X-Content-Type-Options", config.ContentTypeOptions)
+		header.Set("Referrer-Policy", config.ReferrerPolicy)
+		if config.PermissionsPolicy != "" {
+			header.Set("Permissions-Policy", config.PermissionsPolicy)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,97 @@
+package gen
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	engine := New()
+	engine.Use(Secure(SecureConfig{
+		HSTSMaxAge:            time.Hour,
+		HSTSIncludeSubdomains: true,
+		SSLProxyHeaders:       map[string]string{"X-Forwarded-Proto": "https"},
+	}))
+	engine.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	header := w.Header()
+	if header.Get("X-Frame-Options") != "DENY" || header.Get("X-Content-Type-Options") != "nosniff" ||
+		header.Get("Referrer-Policy") != "strict-origin-when-cross-origin" {
+		t.Fatalf("default headers should be set, got %v", header)
+	}
+	if header.Get("Strict-Transport-Security") != "" || header.Get("Content-Security-Policy") != "" || header.Get("Permissions-Policy") != "" {
+		t.Fatalf("HSTS should only be set over HTTPS and empty policies omitted, got %v", header)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if hsts := w.Header().Get("Strict-Transport-Security"); hsts != "max-age=3600; includeSubDomains" {
+		t.Fatalf("HSTS should be set over HTTPS, got %q", hsts)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "HTTPS")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Header().Get("Strict-Transport-Security") == "" {
+		t.Fatal("SSLProxyHeaders should mark the request as HTTPS")
+	}
+}
+
+func TestSecureRedirect(t *testing.T) {
+	engine := New()
+	engine.Use(Secure(SecureConfig{
+		SSLRedirect:     true,
+		SSLProxyHeaders: map[string]string{"X-Forwarded-Proto": "https"},
+		AllowedHosts:    []string{"example.com", "api.example.com:8443"},
+	}))
+	ok := func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	}
+	engine.GET("/users", ok)
+	engine.POST("/users", ok)
+
+	do := func(method string, target string, proto string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if proto != "" {
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "http://example.com/users?id=1", "")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/users?id=1" {
+		t.Fatalf("GET should be redirected with 301, got %d %v", w.Code, w.Header())
+	}
+	if w := do("POST", "http://example.com/users", ""); w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("POST should be redirected with 308, got %d", w.Code)
+	}
+	if w := do("GET", "http://example.com/users", "https"); w.Code != http.StatusOK {
+		t.Fatalf("HTTPS behind proxy should not be redirected, got %d", w.Code)
+	}
+
+	// 不带端口的AllowedHosts匹配任意端口，带端口的需完全一致
+	if w := do("GET", "http://example.com:8080/users", "https"); w.Code != http.StatusOK {
+		t.Fatalf("host with port should match allowed hostname, got %d", w.Code)
+	}
+	if w := do("GET", "http://api.example.com:8443/users", "https"); w.Code != http.StatusOK {
+		t.Fatalf("host should match allowed host with port, got %d", w.Code)
+	}
+	if w := do("GET", "http://api.example.com/users", "https"); w.Code != http.StatusBadRequest {
+		t.Fatalf("host without the allowed port should return 400, got %d", w.Code)
+	}
+	if w := do("GET", "http://evil.com/users", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("disallowed host should return 400 before redirect, got %d", w.Code)
+	}
+}
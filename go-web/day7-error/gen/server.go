@@ -0,0 +1,243 @@
+package gen
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// 优雅退出时，等待进行中请求完成的默认时间
+const defaultShutdownTimeout = 10 * time.Second
+
+// 生命周期钩子，用于启动时初始化资源或退出时刷新缓存、关闭数据库等
+type HookFunc func(ctx context.Context) error
+
+// Engine持有的http.Server，每次Run对应一个
+type server struct {
+	*http.Server
+	once sync.Once
+	done chan struct{} // Shutdown完成后关闭
+	err  error         // Shutdown的结果
+}
+
+// 注册服务启动前执行的钩子，任意一个返回error则不再启动
+func (engine *Engine) OnStart(hook HookFunc) {
+	engine.onStart = append(engine.onStart, hook)
+}
+
+// 注册服务退出时执行的钩子，在连接全部处理完后按注册的逆序执行
+func (engine *Engine) OnShutdown(hook HookFunc) {
+	engine.onShutdown = append(engine.onShutdown, hook)
+}
+
+// 设置优雅退出时等待请求完成的最长时间
+func (engine *Engine) SetShutdownTimeout(timeout time.Duration) {
+	engine.shutdownTimeout = timeout
+}
+
+// 启动服务并阻塞，直到ctx结束、收到SIGINT/SIGTERM或Shutdown被调用
+func (engine *Engine) RunContext(ctx context.Context, addr string) error {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return err
+	}
+	return engine.serve(ctx, srv, srv.ListenAndServe)
+}
+
+// 以HTTPS方式监听，证书为空且配置了WithSelfSignedCert时使用内存中生成的自签名证书
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) error {
+	srv, err := engine.newServer(addr)
+	if err != nil {
+		return err
+	}
+	if certFile == "" && keyFile == "" && len(engine.options.selfSignedHosts) > 0 {
+		cert, err := SelfSignedCertificate(engine.options.selfSignedHosts...)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig.Certificates = append(srv.TLSConfig.Certificates, cert)
+	}
+	return engine.serve(context.Background(), srv, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// 监听unix socket，启动前清理残留的socket文件，退出后删除
+func (engine *Engine) RunUnix(file string) error {
+	if err := removeStaleSocket(file); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	defer os.Remove(file)
+
+	mode := engine.options.unixSocketMode
+	if mode == 0 {
+		mode = 0660
+	}
+	if err := os.Chmod(file, mode); err != nil {
+		return err
+	}
+	return engine.RunListener(listener)
+}
+
+// 只删除没有进程监听的socket文件，路径为普通文件或socket仍在使用时返回错误
+func removeStaleSocket(file string) error {
+	info, err := os.Lstat(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("gen: %s exists and is not a unix socket", file)
+	}
+	if conn, err := net.DialTimeout("unix", file, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("gen: unix socket %s is already in use", file)
+	}
+	return os.Remove(file)
+}
+
+// 使用已有的listener，适用于systemd socket activation及测试
+func (engine *Engine) RunListener(listener net.Listener) error {
+	srv, err := engine.newServer(listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	return engine.serve(context.Background(), srv, func() error {
+		return srv.Serve(listener)
+	})
+}
+
+// 优雅退出：停止接收新连接，等待进行中的请求完成后执行OnShutdown钩子
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.mu.Lock()
+	srv := engine.server
+	engine.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+
+	srv.once.Do(func() {
+		srv.err = srv.Shutdown(ctx)
+		// 等待请求时可能已用完ctx的时间，此时钩子使用单独的超时，保证刷新缓存、关闭数据库等仍能执行
+		hookCtx := ctx
+		if ctx.Err() != nil {
+			var cancel context.CancelFunc
+			hookCtx, cancel = context.WithTimeout(context.Background(), engine.gracefulTimeout())
+			defer cancel()
+		}
+		for i := len(engine.onShutdown) - 1; i >= 0; i-- {
+			if err := engine.onShutdown[i](hookCtx); err != nil && srv.err == nil {
+				srv.err = err
+			}
+		}
+		close(srv.done)
+	})
+	return srv.err
+}
+
+// 根据Engine的配置创建http.Server
+func (engine *Engine) newServer(addr string) (*http.Server, error) {
+	engine.debugWarnings()
+	opts := engine.options
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadTimeout:       opts.readTimeout,
+		ReadHeaderTimeout: opts.readHeaderTimeout,
+		WriteTimeout:      opts.writeTimeout,
+		IdleTimeout:       opts.idleTimeout,
+		MaxHeaderBytes:    opts.maxHeaderBytes,
+		TLSConfig:         &tls.Config{},
+	}
+	if opts.tlsConfig != nil {
+		srv.TLSConfig = opts.tlsConfig.Clone()
+	}
+	if opts.h2c {
+		h2s := &http2.Server{IdleTimeout: opts.idleTimeout}
+		// 注册到srv上，Shutdown时才能优雅关闭h2c连接
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			return nil, err
+		}
+		srv.Handler = h2c.NewHandler(engine, h2s)
+	}
+	return srv, nil
+}
+
+// 执行监听，并负责信号处理及退出流程
+func (engine *Engine) serve(ctx context.Context, hs *http.Server, listen func() error) error {
+	// 先登记server，启动钩子执行期间调用Shutdown也能停止服务
+	srv := &server{Server: hs, done: make(chan struct{})}
+	engine.mu.Lock()
+	engine.server = srv
+	engine.mu.Unlock()
+	defer func() {
+		engine.mu.Lock()
+		if engine.server == srv {
+			engine.server = nil
+		}
+		engine.mu.Unlock()
+	}()
+
+	for _, hook := range engine.onStart {
+		if err := hook(ctx); err != nil {
+			// 之前的钩子可能已申请了资源，同样执行OnShutdown
+			engine.shutdownWithTimeout()
+			return err
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- listen()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-errCh:
+		if err != http.ErrServerClosed {
+			// 监听失败，例如端口被占用，同样执行OnShutdown释放启动时申请的资源
+			engine.shutdownWithTimeout()
+			return err
+		}
+		// Shutdown由外部调用，等待其完成
+		<-srv.done
+		return srv.err
+	case sig := <-quit:
+		logPrintf("Received %s, shutting down", sig)
+	case <-ctx.Done():
+	}
+	return engine.shutdownWithTimeout()
+}
+
+func (engine *Engine) gracefulTimeout() time.Duration {
+	if engine.shutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return engine.shutdownTimeout
+}
+
+func (engine *Engine) shutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), engine.gracefulTimeout())
+	defer cancel()
+	return engine.Shutdown(ctx)
+}
@@ -0,0 +1,471 @@
+package gen
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestRunContextHooks(t *testing.T) {
+	engine := New()
+	var calls []string
+	engine.OnStart(func(ctx context.Context) error {
+		calls = append(calls, "start")
+		return nil
+	})
+	engine.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "flush cache")
+		return nil
+	})
+	engine.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "close db")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- engine.RunContext(ctx, "127.0.0.1:0")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("RunContext should exit gracefully, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RunContext should exit after ctx is done")
+	}
+	if !reflect.DeepEqual(calls, []string{"start", "close db", "flush cache"}) {
+		t.Fatalf("unexpected hook order %v", calls)
+	}
+}
+
+func TestRunContextListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	engine := New()
+	closed := false
+	engine.OnShutdown(func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+	if err := engine.RunContext(context.Background(), listener.Addr().String()); err == nil {
+		t.Fatal("listening on a used address should fail")
+	}
+	if !closed {
+		t.Fatal("OnShutdown hooks should run when listening fails")
+	}
+}
+
+func TestStartHookError(t *testing.T) {
+	engine := New()
+	var events []string
+	engine.OnStart(func(ctx context.Context) error {
+		events = append(events, "open db")
+		return nil
+	})
+	engine.OnStart(func(ctx context.Context) error {
+		return errors.New("cache unavailable")
+	})
+	engine.OnShutdown(func(ctx context.Context) error {
+		events = append(events, "close db")
+		return nil
+	})
+	if err := engine.RunContext(context.Background(), "127.0.0.1:0"); err == nil || err.Error() != "cache unavailable" {
+		t.Fatalf("RunContext should return the hook error, got %v", err)
+	}
+	if !reflect.DeepEqual(events, []string{"open db", "close db"}) {
+		t.Fatalf("OnShutdown hooks should run when OnStart fails, got %v", events)
+	}
+	if engine.server != nil {
+		t.Fatal("server should be cleared after RunContext returns")
+	}
+}
+
+func TestShutdownHookContext(t *testing.T) {
+	engine := New()
+	var hookErr error
+	engine.OnShutdown(func(ctx context.Context) error {
+		hookErr = ctx.Err()
+		return nil
+	})
+	errCh := make(chan error, 1)
+	engine.OnStart(func(ctx context.Context) error {
+		// 模拟等待请求时已用完超时
+		expired, cancel := context.WithCancel(context.Background())
+		cancel()
+		go func() {
+			errCh <- engine.Shutdown(expired)
+		}()
+		return nil
+	})
+	if err := engine.RunContext(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	<-errCh
+	if hookErr != nil {
+		t.Fatalf("OnShutdown hooks should get a live context, got %v", hookErr)
+	}
+}
+
+func TestShutdownDuringStart(t *testing.T) {
+	engine := New()
+	engine.OnStart(func(ctx context.Context) error {
+		return engine.Shutdown(ctx)
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- engine.RunContext(context.Background(), "127.0.0.1:0")
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("RunContext should exit gracefully, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown during OnStart should stop the server")
+	}
+}
+
+func TestRunListener(t *testing.T) {
+	engine := New(WithReadTimeout(time.Second), WithIdleTimeout(time.Second))
+	engine.GET("/ping", func(c *Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- engine.RunListener(listener)
+	}()
+
+	res, err := http.Get("http://" + listener.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("body should be pong, got %s", body)
+	}
+
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("RunListener should exit gracefully, got %v", err)
+	}
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	cert, err := SelfSignedCertificate("localhost", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestH2C(t *testing.T) {
+	engine := New(WithH2C())
+	engine.GET("/proto", func(c *Context) {
+		c.String(http.StatusOK, c.Req.Proto)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go engine.RunListener(listener)
+	defer engine.Shutdown(context.Background())
+
+	// prior knowledge方式的h2c客户端
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	res, err := client.Get("http://" + listener.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("request should be served over HTTP/2, got %s", body)
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	engine := New(WithH2C())
+	engine.GET("/proto", func(c *Context) {
+		c.String(http.StatusOK, c.Req.Proto)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go engine.RunListener(listener)
+	defer engine.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// 通过HTTP/1.1的Upgrade: h2c升级，HTTP2-Settings为空的SETTINGS帧
+	fmt.Fprintf(conn, "GET /proto HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\nHTTP2-Settings: \r\n\r\n", listener.Addr())
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("server should switch to h2c, got %s", res.Status)
+	}
+
+	// 升级后发送连接前言，升级前的请求作为stream 1返回
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		t.Fatal(err)
+	}
+	framer := http2.NewFramer(conn, reader)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	var body []byte
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, ok := frame.(*http2.DataFrame); ok && data.StreamID == 1 {
+			body = append(body, data.Data()...)
+			if data.StreamEnded() {
+				break
+			}
+		}
+	}
+	// h2c升级时保留原始请求，Proto仍为HTTP/1.1，但响应以HTTP/2帧返回
+	if string(body) != "HTTP/1.1" {
+		t.Fatalf("upgraded request should be answered on stream 1, got %s", body)
+	}
+}
+
+// 记录push的目标
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (w *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+func TestPushThroughMiddleware(t *testing.T) {
+	engine := New()
+	cache := NewResponseCache(ResponseCacheConfig{Name: uniqueCacheName()})
+	engine.Use(Compress(), ETag(), Timeout(time.Second), cache.Handler())
+	engine.GET("/", func(c *Context) {
+		if err := c.Push("/app.css", nil); err != nil {
+			t.Errorf("push should reach the underlying writer, got %v", err)
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !reflect.DeepEqual(w.pushed, []string{"/app.css"}) {
+		t.Fatalf("unexpected pushed targets %v", w.pushed)
+	}
+
+	if err := New().CreateContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil)).Push("/app.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("push without support should return ErrNotSupported, got %v", err)
+	}
+}
+
+// 获取一个空闲的端口
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// 服务在goroutine中启动，重试直到可以连接
+func getWithRetry(t *testing.T, client *http.Client, url string) *http.Response {
+	var err error
+	for i := 0; i < 50; i++ {
+		var res *http.Response
+		if res, err = client.Get(url); err == nil {
+			return res
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal(err)
+	return nil
+}
+
+func TestRunTLS(t *testing.T) {
+	cert, err := SelfSignedCertificate("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600)
+
+	run := func(engine *Engine, certFile string, keyFile string, client *http.Client) {
+		engine.GET("/ping", func(c *Context) {
+			c.String(http.StatusOK, "pong")
+		})
+		addr := freeAddr(t)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- engine.RunTLS(addr, certFile, keyFile)
+		}()
+
+		res := getWithRetry(t, client, "https://"+addr+"/ping")
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.TLS == nil || string(body) != "pong" {
+			t.Fatalf("request should be served over TLS, got %q", body)
+		}
+		if err := engine.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errCh; err != nil {
+			t.Fatalf("RunTLS should exit gracefully, got %v", err)
+		}
+	}
+
+	run(New(), certFile, keyFile, client)
+
+	// 自签名证书每次生成，客户端无法预先信任，只校验确实使用了包含该host的证书
+	insecure := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			leaf, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			return leaf.VerifyHostname("127.0.0.1")
+		},
+	}}}
+	run(New(WithSelfSignedCert("127.0.0.1")), "", "", insecure)
+}
+
+func TestRunUnix(t *testing.T) {
+	dir := t.TempDir()
+	// 普通文件不应被删除
+	regular := filepath.Join(dir, "regular")
+	if err := ioutil.WriteFile(regular, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().RunUnix(regular); err == nil {
+		t.Fatal("RunUnix should refuse to replace a regular file")
+	}
+	if _, err := os.Stat(regular); err != nil {
+		t.Fatal("regular file should be kept")
+	}
+
+	// 残留的socket文件应被清理
+	file := filepath.Join(dir, "gen.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: file, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	engine := New(WithUnixSocketMode(0600))
+	engine.GET("/ping", func(c *Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- engine.RunUnix(file)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", file)
+		},
+	}}
+	res := getWithRetry(t, client, "http://unix/ping")
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("body should be pong, got %s", body)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected socket file mode %v", info.Mode())
+	}
+	// socket仍在使用时拒绝启动
+	if err := New().RunUnix(file); err == nil {
+		t.Fatal("RunUnix should refuse to take over a live socket")
+	}
+
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("RunUnix should exit gracefully, got %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("socket file should be removed after shutdown")
+	}
+}
@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 带hash的资源默认缓存一年
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// 静态文件的配置
+type StaticConfig struct {
+	// 目录下没有index.html时列出文件，默认返回404
+	ListDirectory bool
+	// 单页应用模式，不带扩展名的路径找不到文件时返回根目录的index.html
+	SPA bool
+	// Cache-Control中的max-age，为0时不设置
+	MaxAge time.Duration
+	// Cache-Control中加上immutable，用于文件名带hash的资源，MaxAge默认一年
+	Immutable bool
+	// 客户端支持gzip且存在同名的.gz文件时，直接返回预压缩的文件
+	Precompressed bool
+	// 启动时计算所有文件的hash，可通过带hash的文件名访问并以immutable缓存，
+	// 模板中使用{{ asset "css/lovecucu.css" }}获取URL
+	Fingerprint bool
+}
+
+// 将fs.FS（例如embed.FS）的dir子目录转换为http.FileSystem，供StaticFS使用
+func EmbedFS(fsys fs.FS, dir string) http.FileSystem {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(sub)
+}
+
+// 注册静态文件的路由，root为本地目录
+func (group *RouterGroup) Static(relativePath string, root string) {
+	group.StaticFS(relativePath, http.Dir(root))
+}
+
+func (group *RouterGroup) StaticFS(relativePath string, root http.FileSystem) {
+	group.StaticWithConfig(relativePath, root, StaticConfig{})
+}
+
+func (group *RouterGroup) StaticWithConfig(relativePath string, root http.FileSystem, config StaticConfig) {
+	var assets *assetManifest
+	if config.Fingerprint {
+		assets = newAssetManifest(path.Join(group.prefix, relativePath), root)
+		group.engine.assets = append(group.engine.assets, assets)
+	}
+	handler := group.createStaticHandler(root, config, assets)
+	// *filepath不匹配空路径，根目录需单独注册
+	group.GET(relativePath, handler)
+	group.GET(path.Join(relativePath, "/*filepath"), handler)
+}
+
+// 将单个文件注册到relativePath，例如/favicon.ico
+func (group *RouterGroup) StaticFile(relativePath string, file string) {
+	group.StaticFileFS(relativePath, filepath.Base(file), http.Dir(filepath.Dir(file)))
+}
+
+func (group *RouterGroup) StaticFileFS(relativePath string, file string, root http.FileSystem) {
+	name := path.Clean("/" + file)
+	group.GET(relativePath, func(c *Context) {
+		if !serveStaticFile(c, root, name, StaticConfig{}) {
+			staticNotFound(c)
+		}
+	})
+}
+
+// 静态文件处理方法
+func (group *RouterGroup) createStaticHandler(root http.FileSystem, config StaticConfig, assets *assetManifest) HandlerFunc {
+	return func(c *Context) {
+		name := path.Clean("/" + c.Param("filepath"))
+		if assets != nil {
+			// 带hash的文件名内容不会变化，可以永久缓存
+			if original, ok := assets.resolve(name); ok {
+				fileConfig := config
+				fileConfig.Immutable = true
+				fileConfig.MaxAge = 0
+				if serveStaticFile(c, root, original, fileConfig) {
+					return
+				}
+			}
+		}
+		if serveStaticFile(c, root, name, config) {
+			return
+		}
+		if config.SPA && path.Ext(name) == "" && serveStaticFile(c, root, "/index.html", config) {
+			return
+		}
+		staticNotFound(c)
+	}
+}
+
+func staticNotFound(c *Context) {
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+}
+
+// 输出文件或目录，文件不存在时返回false且不写入任何内容
+func serveStaticFile(c *Context, root http.FileSystem, name string, config StaticConfig) bool {
+	f, err := root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	if stat.IsDir() {
+		// 与http.FileServer一致，目录需以/结尾，否则页面中的相对路径会出错
+		if !strings.HasSuffix(c.Req.URL.Path, "/") {
+			target := c.Req.URL.Path + "/"
+			if c.Req.URL.RawQuery != "" {
+				target += "?" + c.Req.URL.RawQuery
+			}
+			http.Redirect(c.Writer, c.Req, target, http.StatusMovedPermanently)
+			c.StatusCode = http.StatusMovedPermanently
+			return true
+		}
+		if serveStaticFile(c, root, path.Join(name, "index.html"), config) {
+			return true
+		}
+		if !config.ListDirectory {
+			return false
+		}
+		req := c.Req.Clone(c.Req.Context())
+		req.URL.Path = strings.TrimSuffix(name, "/") + "/"
+		http.FileServer(root).ServeHTTP(c.Writer, req)
+		c.StatusCode = c.Writer.Status()
+		return true
+	}
+
+	setStaticCacheControl(c, config)
+	if !config.Precompressed || !serveGzipFile(c, root, name, stat.Name()) {
+		http.ServeContent(c.Writer, c.Req, stat.Name(), stat.ModTime(), f)
+	}
+	c.StatusCode = c.Writer.Status()
+	return true
+}
+
+// 存在name.gz且客户端支持gzip时输出预压缩的文件
+func serveGzipFile(c *Context, root http.FileSystem, name string, baseName string) bool {
+	gz, err := root.Open(name + ".gz")
+	if err != nil {
+		return false
+	}
+	defer gz.Close()
+	stat, err := gz.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+
+	header := c.Writer.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if encodingQuality(parseAcceptEncoding(c.Req.Header.Get("Accept-Encoding")), "gzip") <= 0 {
+		return false
+	}
+	// 不设置时ServeContent会根据gzip内容识别为application/x-gzip
+	ctype := mime.TypeByExtension(path.Ext(baseName))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	header.Set("Content-Type", ctype)
+	header.Set("Content-Encoding", "gzip")
+	http.ServeContent(c.Writer, c.Req, baseName, stat.ModTime(), gz)
+	return true
+}
+
+func setStaticCacheControl(c *Context, config StaticConfig) {
+	maxAge := config.MaxAge
+	if config.Immutable && maxAge == 0 {
+		maxAge = immutableMaxAge
+	}
+	if maxAge <= 0 {
+		return
+	}
+	value := "public, max-age=" + strconv.Itoa(int(maxAge/time.Second))
+	if config.Immutable {
+		value += ", immutable"
+	}
+	c.SetHeader("Cache-Control", value)
+}
@@ -0,0 +1,75 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStatic(t *testing.T) {
+	files := fstest.MapFS{
+		"public/index.html":      {Data: []byte("<h1>app</h1>")},
+		"public/css/app.css":     {Data: []byte("body{}")},
+		"public/css/app.css.gz":  {Data: []byte("gzipped")},
+		"public/docs/readme.txt": {Data: []byte("readme")},
+	}
+	engine := New()
+	engine.StaticWithConfig("/assets", EmbedFS(files, "public"), StaticConfig{Immutable: true, Precompressed: true})
+	engine.StaticWithConfig("/list", EmbedFS(files, "public"), StaticConfig{ListDirectory: true})
+	engine.StaticWithConfig("/app", EmbedFS(files, "public"), StaticConfig{SPA: true})
+
+	get := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/assets/css/app.css", "")
+	if w.Code != http.StatusOK || w.Body.String() != "body{}" || w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = get("/assets/css/app.css", "gzip")
+	if w.Body.String() != "gzipped" || w.Header().Get("Content-Encoding") != "gzip" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("precompressed file should be served, got %q %v", w.Body.String(), w.Header())
+	}
+	if w = get("/assets/css/app.css", "gzip;q=0, *"); w.Body.String() != "body{}" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("gzip with q=0 should not be served, got %q %v", w.Body.String(), w.Header())
+	}
+	if w = get("/assets/missing.css", ""); w.Code != http.StatusNotFound || w.Body.String() != "404 NOT FOUND: /assets/missing.css\n" {
+		t.Fatalf("missing file should return 404 with body, got %d %q", w.Code, w.Body.String())
+	}
+	if w = get("/assets/docs/", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("directory listing should be disabled by default, got %d", w.Code)
+	}
+	if w = get("/list/docs/", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "readme.txt") {
+		t.Fatalf("directory listing should be enabled, got %d %q", w.Code, w.Body.String())
+	}
+	if w = get("/list/docs", ""); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/list/docs/" {
+		t.Fatalf("directory without trailing slash should redirect, got %d", w.Code)
+	}
+	if w = get("/app/users/1", ""); w.Code != http.StatusOK || w.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("spa should fall back to index.html, got %d %q", w.Code, w.Body.String())
+	}
+	if w = get("/app/", ""); w.Code != http.StatusOK || w.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("spa root should serve index.html, got %d %q", w.Code, w.Body.String())
+	}
+	if w = get("/app/missing.js", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("missing asset should not fall back, got %d", w.Code)
+	}
+}
+
+func TestStaticFile(t *testing.T) {
+	engine := New()
+	engine.StaticFile("/lovecucu.css", "../static/css/lovecucu.css")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/lovecucu.css", nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("static file should be served, got %d", w.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 超时中间件的配置
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// 超时后返回的状态码，默认503，也可以使用504
+	StatusCode int
+	// 超时后的响应，默认返回{"message": "Service Unavailable"}
+	Handler HandlerFunc
+}
+
+// 为后续handler设置超时，可用于Engine、RouterGroup或单个路由
+func Timeout(d time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+func TimeoutWithConfig(config TimeoutConfig) HandlerFunc {
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	if config.Handler == nil {
+		config.Handler = func(c *Context) {
+			c.Fail(config.StatusCode, http.StatusText(config.StatusCode))
+		}
+	}
+
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), config.Timeout)
+		defer cancel()
+
+		w := c.Writer
+		tw := &timeoutWriter{header: w.Header().Clone(), status: http.StatusOK, pusher: findPusher(w)}
+		// 后续handler在单独的goroutine中使用副本执行，超时后与当前请求互不影响
+		cc := c.clone()
+		cc.Req = c.Req.WithContext(ctx)
+		cc.Writer = tw
+
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			cc.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			// 交给外层的Recovery处理
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			dst := w.Header()
+			for k := range dst {
+				delete(dst, k)
+			}
+			for k, v := range tw.header {
+				dst[k] = v
+			}
+			if tw.wroteHeader || tw.buf.Len() > 0 {
+				w.WriteHeader(tw.status)
+				w.Write(tw.buf.Bytes())
+			}
+			c.Params = cc.Params
+			c.StatusCode = cc.StatusCode
+			c.index = cc.index
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			if ctx.Err() != context.DeadlineExceeded {
+				// 客户端断开连接，无需返回超时响应
+				c.Abort()
+				return
+			}
+			// 使用新的Context返回超时响应，避免与仍在执行的handler竞争
+			tc := c.clone()
+			tc.Writer = w
+			config.Handler(tc)
+			c.StatusCode = w.Status()
+			c.Abort()
+		}
+	}
+}
+
+// 缓存handler的输出，超时后丢弃所有写入
+type timeoutWriter struct {
+	header http.Header
+	pusher http.Pusher // 底层支持server push时不为nil
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.status = code
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.buf.Write(data)
+}
+
+// 与超时响应互斥，超时后不再push
+func (tw *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	if tw.pusher == nil {
+		return http.ErrNotSupported
+	}
+	return tw.pusher.Push(target, opts)
+}
+
+// 输出被缓存到超时或完成，无法流式发送
+func (tw *timeoutWriter) Flush() {}
+
+func (tw *timeoutWriter) Status() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.status
+}
+
+func (tw *timeoutWriter) Size() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.buf.Len()
+}
+
+func (tw *timeoutWriter) Written() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.wroteHeader
+}
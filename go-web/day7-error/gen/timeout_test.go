@@ -0,0 +1,65 @@
+package gen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	engine := New()
+	finished := make(chan struct{})
+	engine.GET("/slow", Timeout(20*time.Millisecond), func(c *Context) {
+		<-c.Req.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		// 超时后的写入不应出现在响应中
+		c.String(http.StatusOK, "late")
+		close(finished)
+	})
+	engine.GET("/fast", Timeout(time.Second), func(c *Context) {
+		c.SetHeader("X-Fast", "1")
+		c.String(http.StatusCreated, "fast")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	<-finished
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("slow request should return 503, got %d", w.Code)
+	}
+	if w.Body.String() != "{\"message\":\"Service Unavailable\"}\n" {
+		t.Fatalf("late write should be discarded, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Fatalf("fast request should pass through, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutClientCanceled(t *testing.T) {
+	engine := New()
+	timedOut := false
+	engine.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout: time.Second,
+		Handler: func(c *Context) {
+			timedOut = true
+			c.Fail(http.StatusServiceUnavailable, "timeout")
+		},
+	}))
+	engine.GET("/slow", func(c *Context) {
+		<-c.Req.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/slow", nil).WithContext(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if timedOut || w.Body.Len() != 0 {
+		t.Fatalf("client disconnect should not be reported as timeout, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,247 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 保存在Context中的key
+const traceKey = "gen.trace"
+
+// 一次操作的耗时记录，可以是整个请求、某个中间件或handler内部的调用
+type Span struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Name       string            `json:"name"`
+	Route      string            `json:"route,omitempty"`
+	Start      time.Time         `json:"start"`
+	Duration   time.Duration     `json:"duration"`
+	Status     int               `json:"status,omitempty"`
+	Error      bool              `json:"error,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	trace *requestTrace // 为nil时表示未开启tracing，所有操作都被忽略
+}
+
+// 设置附加属性
+func (s *Span) SetAttribute(key string, value string) {
+	if s.trace == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]string)
+	}
+	s.Attributes[key] = value
+}
+
+// 标记为失败，err会记录在属性中
+func (s *Span) SetError(err error) {
+	if s.trace == nil || err == nil {
+		return
+	}
+	s.SetAttribute("error.message", err.Error())
+	s.trace.mu.Lock()
+	s.Error = true
+	s.trace.mu.Unlock()
+}
+
+// 结束span
+func (s *Span) End() {
+	if s.trace == nil {
+		return
+	}
+	s.trace.end(s)
+}
+
+// span的导出接口，每个请求结束后调用一次
+type SpanExporter interface {
+	Export(spans []*Span) error
+}
+
+// 单个请求的所有span
+type requestTrace struct {
+	mu     sync.Mutex
+	spans  []*Span
+	active []*Span // 当前正在执行的span，栈顶为最内层
+	traceC TraceContext
+}
+
+// 以当前最内层的span为父节点创建新的span
+func (t *requestTrace) start(name string) *Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &Span{TraceID: t.traceC.TraceID, SpanID: randomHex(8), Name: name, Start: time.Now(), trace: t}
+	if n := len(t.active); n > 0 {
+		span.ParentID = t.active[n-1].SpanID
+	}
+	t.spans = append(t.spans, span)
+	t.active = append(t.active, span)
+	return span
+}
+
+func (t *requestTrace) end(span *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if span.Duration == 0 {
+		span.Duration = time.Since(span.Start)
+	}
+	for i := len(t.active) - 1; i >= 0; i-- {
+		if t.active[i] == span {
+			t.active = append(t.active[:i], t.active[i+1:]...)
+			break
+		}
+	}
+}
+
+// 在当前span下创建子span，未开启tracing时返回的span不做任何记录
+func (c *Context) StartSpan(name string) *Span {
+	value, ok := c.Get(traceKey)
+	if !ok {
+		return &Span{Name: name}
+	}
+	return value.(*requestTrace).start(name)
+}
+
+// 获取handler的函数名，用作span名称
+func handlerName(h HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// 为handler记录span，panic时标记为失败
+func traceHandler(t *requestTrace, h HandlerFunc) HandlerFunc {
+	name := handlerName(h)
+	return func(c *Context) {
+		span := t.start(name)
+		defer func() {
+			if err := recover(); err != nil {
+				span.SetAttribute("panic", fmt.Sprint(err))
+				t.mu.Lock()
+				span.Error = true
+				t.mu.Unlock()
+				span.End()
+				panic(err)
+			}
+			span.End()
+		}()
+		h(c)
+	}
+}
+
+// 链路追踪中间件，为请求及其后的每个中间件和handler生成span，请求结束后交给exporter导出。
+// 与RequestID一起使用时沿用其trace信息，否则新建链路
+func Tracing(exporter SpanExporter) HandlerFunc {
+	return func(c *Context) {
+		traceC := c.TraceContext()
+		if traceC.TraceID == "" {
+			traceC = TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: "01"}
+			c.Set(traceContextKey, traceC)
+		}
+		t := &requestTrace{traceC: traceC}
+		c.Set(traceKey, t)
+
+		root := t.start(c.Method + " " + c.Path)
+		// 根span沿用traceparent中的id，与上游保持关联
+		root.SpanID = traceC.SpanID
+		root.ParentID = traceC.ParentID
+
+		// 包装后续的handler，每一步的耗时都能单独看到
+		handlers := make([]HandlerFunc, len(c.handlers))
+		copy(handlers, c.handlers)
+		for i := c.index + 1; i < len(handlers); i++ {
+			handlers[i] = traceHandler(t, handlers[i])
+		}
+		c.handlers = handlers
+
+		panicked := true
+		defer func() {
+			t.mu.Lock()
+			if c.fullPath != "" {
+				root.Name = c.Method + " " + c.fullPath
+				root.Route = c.fullPath
+			}
+			root.Status = c.Writer.Status()
+			root.Error = panicked || root.Status >= http.StatusInternalServerError
+			t.mu.Unlock()
+			root.End()
+
+			t.mu.Lock()
+			spans := append([]*Span(nil), t.spans...)
+			t.mu.Unlock()
+			if err := exporter.Export(spans); err != nil {
+				logPrintf("export spans failed: %v", err)
+			}
+		}()
+		c.Next()
+		panicked = false
+	}
+}
+
+// 以JSON格式逐行输出span
+type StdoutExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// w为nil时输出到标准输出
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &StdoutExporter{encoder: json.NewEncoder(w)}
+}
+
+func (e *StdoutExporter) Export(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, span := range spans {
+		if err := e.encoder.Encode(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 将span保存在内存中，便于测试
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(spans []*Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+// 已导出的所有span
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
@@ -0,0 +1,42 @@
+package gen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	engine := New()
+	engine.Use(RequestID(), Tracing(exporter))
+	engine.GET("/hello/:name", func(c *Context) {
+		span := c.StartSpan("db.query")
+		span.SetAttribute("db.table", "users")
+		span.End()
+		c.String(http.StatusOK, "hello")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/hello/lovecucu", nil)
+	req.Header.Set(HeaderTraceParent, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	engine.ServeHTTP(w, req)
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("should export request, handler and child spans, got %d", len(spans))
+	}
+	root, handler, child := spans[0], spans[1], spans[2]
+	if root.Name != "GET /hello/:name" || root.Status != http.StatusOK || root.Error {
+		t.Fatalf("unexpected root span %+v", root)
+	}
+	if root.TraceID != "0af7651916cd43dd8448eb211c80319c" || root.ParentID != "b7ad6b7169203331" {
+		t.Fatal("root span should continue the incoming trace")
+	}
+	if handler.ParentID != root.SpanID || child.ParentID != handler.SpanID {
+		t.Fatal("spans should be nested by the handler chain")
+	}
+	if child.Name != "db.query" || child.Attributes["db.table"] != "users" {
+		t.Fatalf("unexpected child span %+v", child)
+	}
+}
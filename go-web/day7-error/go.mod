@@ -1,10 +1,19 @@
 module day3-router
 
-go 1.14
+go 1.19
 
 replace gen => ./gen
 
+replace gencache => ../../go-cache/day7-protobuf/gencache
+
 require (
 	gen v0.0.0
 	github.com/golang/protobuf v1.5.2
 )
+
+require (
+	gencache v0.0.0 // indirect
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/protobuf v1.26.0 // indirect
+)